@@ -1,6 +1,7 @@
 package tdx
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/injoyai/base/maps"
@@ -11,7 +12,10 @@ import (
 	"github.com/injoyai/ios/module/common"
 	"github.com/injoyai/logs"
 	"github.com/injoyai/tdx/protocol"
+	"io"
 	"runtime/debug"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -39,6 +43,13 @@ func WithLevel(level int) client.Option {
 	}
 }
 
+// WithHEX 通讯数据以HEX显示,需要日志级别包含读写,例WithLevel(LevelAll)
+func WithHEX() client.Option {
+	return func(c *client.Client) {
+		c.Logger.WithHEX()
+	}
+}
+
 // WithRedial 断线重连
 func WithRedial(b ...bool) client.Option {
 	return func(c *client.Client) {
@@ -46,6 +57,104 @@ func WithRedial(b ...bool) client.Option {
 	}
 }
 
+// WithReconnect 断线重连的退避策略,第一次立即重连,失败后等待start,每次乘以multi,最多等待max,
+// 默认2秒到32秒,倍数2,需要开启WithRedial
+func WithReconnect(start, max time.Duration, multi uint8) client.Option {
+	return func(c *client.Client) {
+		c.Event.OnReconnect = client.NewReconnectRetreat(start, max, multi)
+	}
+}
+
+// tagClient ios客户端的Tag中保存*Client,用于下面设置Client的选项,只对DialWith建立的客户端生效
+const tagClient = "tdx.Client"
+
+func withClient(f func(c *Client)) client.Option {
+	return func(c *client.Client) {
+		if v, ok := c.Tag.Get(tagClient); ok {
+			if cli, ok := v.(*Client); ok {
+				f(cli)
+			}
+		}
+	}
+}
+
+// WithHeartbeat 心跳间隔,服务器60秒没有数据会断开,默认30秒,小于等于0表示不发送心跳
+func WithHeartbeat(t time.Duration) client.Option {
+	return withClient(func(c *Client) {
+		c.heartbeat = t
+	})
+}
+
+// WithTimeout 等待响应的超时时间,默认2秒,同SetTimeout
+func WithTimeout(t time.Duration) client.Option {
+	return withClient(func(c *Client) {
+		c.SetTimeout(t)
+	})
+}
+
+// WithTypeTimeout 指定消息类型等待响应的超时时间,例如历史分笔在慢速网络下需要更长的时间,
+// 例WithTypeTimeout(protocol.TypeHistoryMinuteTrade, 10*time.Second),同SetTypeTimeout
+func WithTypeTimeout(Type uint16, t time.Duration) client.Option {
+	return withClient(func(c *Client) {
+		c.SetTypeTimeout(Type, t)
+	})
+}
+
+// WithMaxInflight 最多同时等待响应的请求数量,超过的等待前面的请求完成,默认不限制
+func WithMaxInflight(n int) client.Option {
+	return withClient(func(c *Client) {
+		c.inflight = nil
+		if n > 0 {
+			c.inflight = make(chan struct{}, n)
+		}
+	})
+}
+
+// WithOnConnect 连接成功(包括重连)并发送握手后执行,不要在回调中阻塞
+func WithOnConnect(f func(c *Client)) client.Option {
+	return withClient(func(c *Client) {
+		c.onConnect = append(c.onConnect, f)
+	})
+}
+
+// WithOnDisconnect 连接断开后执行,开启重连时之后会自动重连,不要在回调中阻塞
+func WithOnDisconnect(f func(c *Client, err error)) client.Option {
+	return withClient(func(c *Client) {
+		c.onDisconnect = append(c.onDisconnect, f)
+	})
+}
+
+// WithBjCodes 北交所代码的来源,默认GetBjCodes从北交所官网获取,可用于离线测试或者自定义缓存
+func WithBjCodes(f func() ([]*BjCode, error)) client.Option {
+	return withClient(func(c *Client) {
+		c.bjCodes = f
+	})
+}
+
+// setEvent 执行自定义选项(可以包装上面的事件,例如WithRecord),并设置连接和断开的事件,
+// 内置的握手,心跳和连接状态先执行,选项中设置的OnConnected/OnDisconnect在之后执行,不会替换内置的事件
+func setEvent(c *client.Client, op []client.Option, onConnected func(c *client.Client) error, onDisconnect func(c *client.Client, err error)) {
+	c.Event.OnConnected = nil
+	c.Event.OnDisconnect = nil
+	c.SetOption(op...)
+	connected, disconnect := c.Event.OnConnected, c.Event.OnDisconnect
+	c.Event.OnConnected = func(c *client.Client) error {
+		if err := onConnected(c); err != nil {
+			return err
+		}
+		if connected != nil {
+			return connected(c)
+		}
+		return nil
+	}
+	c.Event.OnDisconnect = func(c *client.Client, err error) {
+		onDisconnect(c, err)
+		if disconnect != nil {
+			disconnect(c, err)
+		}
+	}
+}
+
 // DialDefault 默认连接方式
 func DialDefault(op ...client.Option) (cli *Client, err error) {
 	op = append([]client.Option{WithRedial()}, op...)
@@ -75,61 +184,125 @@ func DialHostsRange(hosts []string, op ...client.Option) (cli *Client, err error
 // DialWith 与服务器建立连接
 func DialWith(dial ios.DialFunc, op ...client.Option) (cli *Client, err error) {
 
+	ctx, cancel := context.WithCancel(context.Background())
 	cli = &Client{
-		Wait: wait.New(time.Second * 2),
-		m:    maps.NewSafe(),
+		Wait:      wait.New(time.Second * 2),
+		m:         maps.NewSafe(),
+		timeout:   time.Second * 2,
+		timeouts:  maps.NewGeneric[uint16, time.Duration](),
+		heartbeat: time.Second * 30,
+		running:   make(chan struct{}),
+		cancel:    cancel,
 	}
 
-	cli.Client, err = client.Dial(dial, func(c *client.Client) {
-		c.Logger.Debug(true)                           //关闭日志打印
-		c.Logger.SetLevel(LevelInfo)                   //设置日志级别
-		c.Logger.WithHEX()                             //以HEX显示
-		c.SetOption(op...)                             //自定义选项
+	cli.Client, err = client.Dial(func(ctx context.Context) (ios.ReadWriteCloser, string, error) {
+		if cli.started {
+			//读取协程中重连,说明已经完成初始化
+			cli.ready()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		return dial(ctx)
+	}, func(c *client.Client) {
+		cli.Client = c                                 //回调中会用到,和返回的是同一个实例
+		c.Tag.Set(tagClient, cli)                      //用于设置Client的选项,例如WithHeartbeat
+		c.Logger.SetLevel(LevelInfo)                   //设置日志级别,通讯数据通过WithLevel和WithHEX设置
 		c.Event.OnReadFrom = protocol.ReadFrom         //分包
 		c.Event.OnDealMessage = cli.handlerDealMessage //解析数据并处理
-		c.Event.OnConnected = func(c *client.Client) error {
-			//无数据超时时间是60秒,30秒发送一个心跳包
-			c.GoTimerWriter(30*time.Second, func(w ios.MoreWriter) error {
-				bs := protocol.MHeart.Frame().Bytes()
-				_, err := w.Write(bs)
-				return err
-			})
+		setEvent(c, op, func(c *client.Client) error {
+			cli.state.connect()
+			//无数据超时时间是60秒,默认30秒发送一个心跳包
+			if cli.heartbeat > 0 {
+				c.GoTimerWriter(cli.heartbeat, func(w ios.MoreWriter) error {
+					bs := protocol.MHeart.Frame().Bytes()
+					_, err := cli.state.write(w, bs)
+					return err
+				})
+			}
 			f := protocol.MConnect.Frame()
-			if _, err = c.Write(f.Bytes()); err != nil {
+			if _, err := cli.state.write(c, f.Bytes()); err != nil {
 				c.Close()
+				return nil
+			}
+			for _, f := range cli.onConnect {
+				f(cli)
 			}
 			return nil
+		}, func(c *client.Client, err error) {
+			cli.state.disconnect(cli.Wait)
+			for _, f := range cli.onDisconnect {
+				f(cli, err)
+			}
+		})
+		//读取协程第一次读取数据时,初始化已经完成,之后关闭连接不会和初始化同时进行
+		readFrom := c.Event.OnReadFrom
+		c.Event.OnReadFrom = func(r io.Reader) ([]byte, error) {
+			cli.ready()
+			return readFrom(r)
 		}
 	})
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	go cli.Client.Run()
+	cli.started = true
+	go func() {
+		cli.Client.Run(ctx)
+		cli.ready()
+	}()
 
 	return cli, err
 }
 
 type Client struct {
-	*client.Client              //客户端实例
-	Wait           *wait.Entity //异步回调,设置超时时间,超时则返回错误
-	m              *maps.Safe   //有部分解析需要用到代码,返回数据获取不到,固请求的时候缓存下
-	msgID          uint32       //消息id,使用SendFrame自动累加
+	*client.Client                                      //客户端实例
+	Wait           *wait.Entity                         //异步回调,设置超时时间,超时则返回错误
+	m              *maps.Safe                           //有部分解析需要用到代码,返回数据获取不到,固请求的时候缓存下
+	msgID          uint32                               //消息id,使用SendFrame自动累加
+	timeout        time.Duration                        //等待响应的超时时间,通过SetTimeout设置
+	timeouts       *maps.Generic[uint16, time.Duration] //按消息类型设置的超时时间,通过SetTypeTimeout设置
+	heartbeat      time.Duration                        //心跳间隔,通过WithHeartbeat设置
+	inflight       chan struct{}                        //限制同时等待响应的请求数量,通过WithMaxInflight设置
+	onConnect      []func(c *Client)                    //连接成功的回调,通过WithOnConnect设置
+	onDisconnect   []func(c *Client, err error)         //断开连接的回调,通过WithOnDisconnect设置
+	state          connState                            //连接状态和等待响应的请求
+	codes          CodeResolver                         //代码信息,补全交易所前缀和价格小数位,通过SetCodeResolver设置
+	hosts          *HostManager                         //服务地址管理,通过DialHostManager设置
+	bjCodes        func() ([]*BjCode, error)            //北交所代码的来源,通过WithBjCodes设置
+	started        bool                                 //读取协程是否已经启动
+	running        chan struct{}                        //读取协程完成初始化(或已退出)后关闭
+	runOnce        sync.Once                            //只关闭一次running
+	cancel         context.CancelFunc                   //结束读取协程,不再重连,通过CloseAll调用
 }
 
 // handlerDealMessage 处理服务器响应的数据
 func (this *Client) handlerDealMessage(c *client.Client, msg ios.Acker) {
 
+	var f *protocol.Response
 	defer func() {
 		if e := recover(); e != nil {
 			logs.Err(e)
 			debug.PrintStack()
+			if f != nil {
+				//解析异常也通知等待的请求,避免一直等到超时
+				this.Wait.Done(conv.String(f.MsgID), nil, &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: fmt.Errorf("%v", e)})
+			}
 		}
 	}()
 
 	f, err := protocol.Decode(msg.Payload())
 	if err != nil {
 		logs.Err(err)
+		if f != nil {
+			//帧头完整,通知等待的请求
+			this.m.Del(conv.String(f.MsgID))
+			if !errors.Is(err, protocol.ErrServerRejected) {
+				err = &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: err}
+			}
+			this.Wait.Done(conv.String(f.MsgID), nil, err)
+		}
 		return
 	}
 
@@ -150,22 +323,43 @@ func (this *Client) handlerDealMessage(c *client.Client, msg ios.Acker) {
 		resp, err = protocol.MCode.Decode(f.Data)
 
 	case protocol.TypeQuote:
-		resp = protocol.MQuote.Decode(f.Data)
+		resp, err = decodeCache(val, f.Data, protocol.MQuote.Decode)
 
 	case protocol.TypeMinute:
-		resp, err = protocol.MMinute.Decode(f.Data)
+		resp, err = decodeCache(val, f.Data, protocol.MMinute.Decode)
 
 	case protocol.TypeHistoryMinute:
-		resp, err = protocol.MHistoryMinute.Decode(f.Data)
+		resp, err = decodeCache(val, f.Data, protocol.MHistoryMinute.Decode)
 
 	case protocol.TypeMinuteTrade:
-		resp, err = protocol.MTrade.Decode(f.Data, val.(protocol.TradeCache))
+		resp, err = decodeCache(val, f.Data, protocol.MTrade.Decode)
 
 	case protocol.TypeHistoryMinuteTrade:
-		resp, err = protocol.MHistoryTrade.Decode(f.Data, val.(protocol.TradeCache))
+		resp, err = decodeCache(val, f.Data, protocol.MHistoryTrade.Decode)
 
 	case protocol.TypeKline:
-		resp, err = protocol.MKline.Decode(f.Data, val.(protocol.KlineCache))
+		resp, err = decodeCache(val, f.Data, protocol.MKline.Decode)
+
+	case protocol.TypeXdXr:
+		resp, err = protocol.MXdXr.Decode(f.Data)
+
+	case protocol.TypeFinance:
+		resp, err = protocol.MFinance.Decode(f.Data)
+
+	case protocol.TypeCompanyCategory:
+		resp, err = protocol.MCompanyCategory.Decode(f.Data)
+
+	case protocol.TypeCompanyContent:
+		resp, err = protocol.MCompanyContent.Decode(f.Data)
+
+	case protocol.TypeBlockMeta:
+		resp, err = protocol.MBlockMeta.Decode(f.Data)
+
+	case protocol.TypeBlock:
+		resp, err = protocol.MBlock.Decode(f.Data)
+
+	case protocol.TypeAuction:
+		resp, err = decodeCache(val, f.Data, protocol.MAuction.Decode)
 
 	default:
 		err = fmt.Errorf("通讯类型未解析:0x%X", f.Type)
@@ -174,6 +368,7 @@ func (this *Client) handlerDealMessage(c *client.Client, msg ios.Acker) {
 
 	if err != nil {
 		logs.Err(err)
+		this.Wait.Done(conv.String(f.MsgID), nil, &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: err})
 		return
 	}
 
@@ -181,41 +376,184 @@ func (this *Client) handlerDealMessage(c *client.Client, msg ios.Acker) {
 
 }
 
+// Close 断开连接,开启重连时会重新连接,
+// 读取协程还在初始化时会等待其完成,避免关闭和初始化同时修改连接的状态
+func (this *Client) Close() error {
+	this.waitRunning()
+	return this.Client.Close()
+}
+
+// CloseAll 断开连接,并不再重连,
+// 通过上下文结束读取协程,不修改重连的设置,避免和读取协程同时读写
+func (this *Client) CloseAll() error {
+	this.cancel()
+	this.waitRunning()
+	return this.Client.Close()
+}
+
+// ready 读取协程完成初始化(或已退出)
+func (this *Client) ready() {
+	this.runOnce.Do(func() { close(this.running) })
+}
+
+// waitRunning 等待读取协程完成初始化,
+// 连接建立时的回调在读取协程启动前执行,这时无需等待
+func (this *Client) waitRunning() {
+	if this.started {
+		<-this.running
+	}
+}
+
+// Closed 连接是否已断开,开启重连时重连成功后恢复
+func (this *Client) Closed() bool {
+	return this.state.closed()
+}
+
 // SetTimeout 设置超时时间
 func (this *Client) SetTimeout(t time.Duration) {
+	this.timeout = t
 	this.Wait.SetTimeout(t)
 }
 
+// SetTypeTimeout 设置指定消息类型的超时时间,例如protocol.TypeHistoryMinuteTrade,小于等于0表示使用SetTimeout的时间
+func (this *Client) SetTypeTimeout(Type uint16, t time.Duration) {
+	if t <= 0 {
+		this.timeouts.Del(Type)
+		return
+	}
+	this.timeouts.Set(Type, t)
+}
+
+// timeoutOf 消息类型的超时时间
+func (this *Client) timeoutOf(Type uint16) time.Duration {
+	if t, ok := this.timeouts.Get(Type); ok {
+		return t
+	}
+	return this.timeout
+}
+
+// SetCodeResolver 设置代码信息,例*Codes,未设置时只能按规则推断交易所和价格小数位
+func (this *Client) SetCodeResolver(r CodeResolver) {
+	this.codes = r
+}
+
+// Symbol 解析代码,支持的格式见protocol.ParseSymbol,只有6位代码时优先使用代码信息补全交易所
+func (this *Client) Symbol(code string) (protocol.Symbol, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 && this.codes != nil {
+		code = this.codes.AddExchange(code)
+	}
+	return protocol.ParseSymbol(code)
+}
+
+// addExchange 统一成通达信的代码格式,例000001.SZ转换成sz000001,解析失败原样返回,由后续的请求返回错误
+func (this *Client) addExchange(code string) string {
+	if s, err := this.Symbol(code); err == nil {
+		return s.String()
+	}
+	return code
+}
+
+// decimal 价格的小数位,优先使用代码信息中服务器返回的小数位,没有的话根据代码推断
+func (this *Client) decimal(code string) int8 {
+	if this.codes != nil {
+		if m := this.codes.Get(code); m != nil && m.Decimal > 0 {
+			return m.Decimal
+		}
+	}
+	return protocol.PriceDecimal(code)
+}
+
 // SendFrame 发送数据,并等待响应
 func (this *Client) SendFrame(f *protocol.Frame, cache ...any) (any, error) {
+	return this.SendFrameContext(context.Background(), f, cache...)
+}
+
+// SendFrameContext 发送数据,并等待响应,上下文取消时立即释放等待并返回上下文的错误,
+// 通过DialHostManager建立的连接会记录结果,连续超时后断开并重连到下一个服务地址
+func (this *Client) SendFrameContext(ctx context.Context, f *protocol.Frame, cache ...any) (any, error) {
+	result, err := this.sendFrame(ctx, f, cache...)
+	if this.hosts != nil && this.hosts.Record(this.GetKey(), err) {
+		logs.Errf("[%s] 切换服务地址...\n", this.GetKey())
+		this.Close()
+	}
+	return result, err
+}
+
+func (this *Client) sendFrame(ctx context.Context, f *protocol.Frame, cache ...any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if this.inflight != nil {
+		select {
+		case this.inflight <- struct{}{}:
+			defer func() { <-this.inflight }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	timeout := this.timeoutOf(f.Type)
 	f.MsgID = atomic.AddUint32(&this.msgID, 1)
+	key := conv.String(f.MsgID)
 	if len(cache) > 0 {
-		this.m.Set(conv.String(f.MsgID), cache[0])
+		this.m.Set(key, cache[0])
 	}
-	if _, err := this.Client.Write(f.Bytes()); err != nil {
+	ch := register(this.Wait, key, timeout)
+	this.state.add(key)
+	defer this.state.del(key)
+	if this.Closed() {
+		this.Wait.Done(key, nil, ErrClosed)
+		this.m.Del(key)
+		return nil, ErrClosed
+	}
+	if _, err := this.state.write(this.Client, f.Bytes()); err != nil {
+		this.Wait.Done(key, nil, err)
+		this.m.Del(key)
+		if this.Closed() {
+			return nil, ErrClosed
+		}
 		return nil, err
 	}
-	return this.Wait.Wait(conv.String(f.MsgID))
+	return waitContext(ctx, this.Wait, key, ch, timeout, func() { this.m.Del(key) })
 }
 
 // GetCount 获取市场内的股票数量
 func (this *Client) GetCount(exchange protocol.Exchange) (*protocol.CountResp, error) {
+	return this.GetCountContext(context.Background(), exchange)
+}
+
+// GetCountContext 同GetCount,支持通过上下文取消
+func (this *Client) GetCountContext(ctx context.Context, exchange protocol.Exchange) (*protocol.CountResp, error) {
 	f := protocol.MCount.Frame(exchange)
-	result, err := this.SendFrame(f)
+	result, err := this.SendFrameContext(ctx, f)
 	if err != nil {
 		return nil, err
 	}
 	return result.(*protocol.CountResp), nil
 }
 
+// Ping 检查连接是否可用,请求上海的证券数量,返回耗时
+func (this *Client) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if _, err := this.GetCountContext(ctx, protocol.ExchangeSH); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
 // GetCode 获取市场内指定范围内的所有证券代码,一次固定返回1000只,上证股票有效范围370-1480
 // 上证前370只是395/399开头的(中证500/总交易等辅助类),在后面的话是一些100开头的国债
 // 600开头的股票是上证A股，属于大盘股，其中6006开头的股票是最早上市的股票， 6016开头的股票为大盘蓝筹股；900开头的股票是上证B股；
 // 000开头的股票是深证A股，001、002开头的股票也都属于深证A股， 其中002开头的股票是深证A股中小企业股票；200开头的股票是深证B股；
 // 300开头的股票是创业板股票；400开头的股票是三板市场股票。
 func (this *Client) GetCode(exchange protocol.Exchange, start uint16) (*protocol.CodeResp, error) {
+	return this.GetCodeContext(context.Background(), exchange, start)
+}
+
+// GetCodeContext 同GetCode,支持通过上下文取消
+func (this *Client) GetCodeContext(ctx context.Context, exchange protocol.Exchange, start uint16) (*protocol.CodeResp, error) {
 	f := protocol.MCode.Frame(exchange, start)
-	result, err := this.SendFrame(f)
+	result, err := this.SendFrameContext(ctx, f)
 	if err != nil {
 		return nil, err
 	}
@@ -224,13 +562,22 @@ func (this *Client) GetCode(exchange protocol.Exchange, start uint16) (*protocol
 
 // GetCodeAll 通过多次请求的方式获取全部证券代码
 func (this *Client) GetCodeAll(exchange protocol.Exchange) (*protocol.CodeResp, error) {
+	return this.GetCodeAllContext(context.Background(), exchange)
+}
+
+// GetCodeAllContext 同GetCodeAll,支持通过上下文取消
+func (this *Client) GetCodeAllContext(ctx context.Context, exchange protocol.Exchange) (*protocol.CodeResp, error) {
 	resp := &protocol.CodeResp{}
 
 	//通达信没有北交所代码列表,通过爬虫的方式从北交所官网获取,放在这里是为了方便业务逻辑
 	//不放在extend包时防止循环引用
 	//todo 这是临时方案,等通达信有北交所代码列表时再改
 	if exchange == protocol.ExchangeBJ {
-		codes, err := GetBjCodes()
+		getBjCodes := this.bjCodes
+		if getBjCodes == nil {
+			getBjCodes = GetBjCodes
+		}
+		codes, err := getBjCodes()
 		if err != nil {
 			return nil, err
 		}
@@ -247,7 +594,11 @@ func (this *Client) GetCodeAll(exchange protocol.Exchange) (*protocol.CodeResp,
 
 	size := uint16(1000)
 	for start := uint16(0); ; start += size {
-		r, err := this.GetCode(exchange, start)
+		//每页请求前判断上下文,取消后不再继续翻页
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetCodeContext(ctx, exchange, start)
 		if err != nil {
 			return nil, err
 		}
@@ -262,9 +613,14 @@ func (this *Client) GetCodeAll(exchange protocol.Exchange) (*protocol.CodeResp,
 
 // GetStockAll 获取所有股票代码
 func (this *Client) GetStockAll() ([]string, error) {
+	return this.GetStockAllContext(context.Background())
+}
+
+// GetStockAllContext 同GetStockAll,支持通过上下文取消
+func (this *Client) GetStockAllContext(ctx context.Context) ([]string, error) {
 	ls := []string(nil)
 	for _, ex := range []protocol.Exchange{protocol.ExchangeSH, protocol.ExchangeSZ, protocol.ExchangeBJ} {
-		resp, err := this.GetCodeAll(ex)
+		resp, err := this.GetCodeAllContext(ctx, ex)
 		if err != nil {
 			return nil, err
 		}
@@ -279,9 +635,14 @@ func (this *Client) GetStockAll() ([]string, error) {
 
 // GetETFAll 获取所有ETF代码
 func (this *Client) GetETFAll() ([]string, error) {
+	return this.GetETFAllContext(context.Background())
+}
+
+// GetETFAllContext 同GetETFAll,支持通过上下文取消
+func (this *Client) GetETFAllContext(ctx context.Context) ([]string, error) {
 	ls := []string(nil)
 	for _, ex := range []protocol.Exchange{protocol.ExchangeSH, protocol.ExchangeSZ} {
-		resp, err := this.GetCodeAll(ex)
+		resp, err := this.GetCodeAllContext(ctx, ex)
 		if err != nil {
 			return nil, err
 		}
@@ -296,15 +657,17 @@ func (this *Client) GetETFAll() ([]string, error) {
 
 // GetQuote 获取盘口五档报价
 func (this *Client) GetQuote(codes ...string) (protocol.QuotesResp, error) {
+	return this.GetQuoteContext(context.Background(), codes...)
+}
+
+// GetQuoteContext 同GetQuote,支持通过上下文取消
+func (this *Client) GetQuoteContext(ctx context.Context, codes ...string) (protocol.QuotesResp, error) {
+	//复制一份,不修改调用方的参数
+	codes = append([]string(nil), codes...)
 	for i := range codes {
-		//如果是股票代码,则加上前缀
-		codes[i] = protocol.AddPrefix(codes[i])
-		if !protocol.IsStock(codes[i]) {
-			if DefaultCodes == nil {
-				return nil, errors.New("DefaultCodes未初始化")
-			}
-			//不是股票代码的话，根据codes的信息加上前缀
-			codes[i] = DefaultCodes.AddExchange(codes[i])
+		codes[i] = this.addExchange(codes[i])
+		if len(codes[i]) == 6 {
+			return nil, fmt.Errorf("%w,无法确定代码[%s]的交易所,请加上前缀或者通过SetCodeResolver设置代码信息", ErrInvalidCode, codes[i])
 		}
 	}
 
@@ -312,36 +675,25 @@ func (this *Client) GetQuote(codes ...string) (protocol.QuotesResp, error) {
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f)
+	c := protocol.QuoteCache{Decimals: map[string]int8{}}
+	for _, code := range codes {
+		c.Decimals[code] = this.decimal(code)
+	}
+	result, err := this.SendFrameContext(ctx, f, c)
 	if err != nil {
 		return nil, err
 	}
 	quotes := result.(protocol.QuotesResp)
 
-	{ //todo 临时处理下先,后续优化,感觉有问题
-		//判断长度和预期是否一致
-		if len(quotes) != len(codes) {
-			return nil, fmt.Errorf("预期%d个，实际%d个", len(codes), len(quotes))
-		}
-		for i, code := range codes {
-			if !protocol.IsStock(code) {
-				m := DefaultCodes.Get(code)
-				if m == nil {
-					return nil, fmt.Errorf("未查询到代码[%s]相关信息", code)
-				}
-				for ii, v := range quotes[i].SellLevel {
-					quotes[i].SellLevel[ii].Price = m.Price(v.Price)
-				}
-				for ii, v := range quotes[i].BuyLevel {
-					quotes[i].BuyLevel[ii].Price = m.Price(v.Price)
-				}
-				quotes[i].K = protocol.K{
-					Last:  m.Price(quotes[i].K.Last),
-					Open:  m.Price(quotes[i].K.Open),
-					High:  m.Price(quotes[i].K.High),
-					Low:   m.Price(quotes[i].K.Low),
-					Close: m.Price(quotes[i].K.Close),
-				}
+	//判断长度和预期是否一致
+	if len(quotes) != len(codes) {
+		return nil, fmt.Errorf("预期%d个，实际%d个", len(codes), len(quotes))
+	}
+	for i, code := range codes {
+		//ST股票的涨跌幅限制是5%,只能通过名称判断,未设置CodeResolver时按普通股票计算
+		if protocol.IsStock(code) && this.codes != nil {
+			if m := this.codes.Get(code); m != nil && protocol.IsST(m.Name) {
+				quotes[i].EstimatedLimitUp, quotes[i].EstimatedLimitDown = protocol.LimitPrice(code, quotes[i].K.Last, true)
 			}
 		}
 	}
@@ -349,15 +701,23 @@ func (this *Client) GetQuote(codes ...string) (protocol.QuotesResp, error) {
 	return quotes, nil
 }
 
-// GetMinute 获取分时数据,todo 解析好像不对,先用历史数据
+// GetMinute 获取当天的分时数据,包含均价
 func (this *Client) GetMinute(code string) (*protocol.MinuteResp, error) {
-	return this.GetHistoryMinute(time.Now().Format("20060102"), code)
+	return this.GetMinuteContext(context.Background(), code)
+}
 
+// GetMinuteContext 同GetMinute,支持通过上下文取消
+func (this *Client) GetMinuteContext(ctx context.Context, code string) (*protocol.MinuteResp, error) {
+	code = this.addExchange(code)
 	f, err := protocol.MMinute.Frame(code)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f)
+	result, err := this.SendFrameContext(ctx, f, protocol.MinuteCache{
+		Date:    time.Now().In(protocol.LocationCST).Format("20060102"),
+		Code:    code,
+		Decimal: this.decimal(code),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -366,11 +726,21 @@ func (this *Client) GetMinute(code string) (*protocol.MinuteResp, error) {
 
 // GetHistoryMinute 获取历史分时数据
 func (this *Client) GetHistoryMinute(date, code string) (*protocol.MinuteResp, error) {
+	return this.GetHistoryMinuteContext(context.Background(), date, code)
+}
+
+// GetHistoryMinuteContext 同GetHistoryMinute,支持通过上下文取消
+func (this *Client) GetHistoryMinuteContext(ctx context.Context, date, code string) (*protocol.MinuteResp, error) {
+	code = this.addExchange(code)
 	f, err := protocol.MHistoryMinute.Frame(date, code)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f)
+	result, err := this.SendFrameContext(ctx, f, protocol.MinuteCache{
+		Date:    date,
+		Code:    code,
+		Decimal: this.decimal(code),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -383,14 +753,20 @@ func (this *Client) GetTrade(code string, start, count uint16) (*protocol.TradeR
 
 // GetMinuteTrade 获取分时交易详情,服务器最多返回1800条,count-start<=1800
 func (this *Client) GetMinuteTrade(code string, start, count uint16) (*protocol.TradeResp, error) {
-	code = protocol.AddPrefix(code)
+	return this.GetMinuteTradeContext(context.Background(), code, start, count)
+}
+
+// GetMinuteTradeContext 同GetMinuteTrade,支持通过上下文取消
+func (this *Client) GetMinuteTradeContext(ctx context.Context, code string, start, count uint16) (*protocol.TradeResp, error) {
+	code = this.addExchange(code)
 	f, err := protocol.MTrade.Frame(code, start, count)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f, protocol.TradeCache{
-		Date: time.Now().Format("20060102"),
-		Code: code,
+	result, err := this.SendFrameContext(ctx, f, protocol.TradeCache{
+		Date:    time.Now().In(protocol.LocationCST).Format("20060102"),
+		Code:    code,
+		Decimal: this.decimal(code),
 	})
 	if err != nil {
 		return nil, err
@@ -404,10 +780,18 @@ func (this *Client) GetTradeAll(code string) (*protocol.TradeResp, error) {
 
 // GetMinuteTradeAll 获取分时全部交易详情,todo 只做参考 因为交易实时在进行,然后又是分页读取的,所以会出现读取间隔内产生的交易会丢失
 func (this *Client) GetMinuteTradeAll(code string) (*protocol.TradeResp, error) {
+	return this.GetMinuteTradeAllContext(context.Background(), code)
+}
+
+// GetMinuteTradeAllContext 同GetMinuteTradeAll,支持通过上下文取消
+func (this *Client) GetMinuteTradeAllContext(ctx context.Context, code string) (*protocol.TradeResp, error) {
 	resp := &protocol.TradeResp{}
 	size := uint16(1800)
 	for start := uint16(0); ; start += size {
-		r, err := this.GetMinuteTrade(code, start, size)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetMinuteTradeContext(ctx, code, start, size)
 		if err != nil {
 			return nil, err
 		}
@@ -429,14 +813,20 @@ func (this *Client) GetHistoryTrade(date, code string, start, count uint16) (*pr
 // 只能获取昨天及之前的数据,服务器最多返回2000条,count-start<=2000,如果日期输入错误,则返回0
 // 历史数据只能查到20000609
 func (this *Client) GetHistoryMinuteTrade(date, code string, start, count uint16) (*protocol.TradeResp, error) {
-	code = protocol.AddPrefix(code)
+	return this.GetHistoryMinuteTradeContext(context.Background(), date, code, start, count)
+}
+
+// GetHistoryMinuteTradeContext 同GetHistoryMinuteTrade,支持通过上下文取消
+func (this *Client) GetHistoryMinuteTradeContext(ctx context.Context, date, code string, start, count uint16) (*protocol.TradeResp, error) {
+	code = this.addExchange(code)
 	f, err := protocol.MHistoryTrade.Frame(date, code, start, count)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f, protocol.TradeCache{
-		Date: date,
-		Code: code,
+	result, err := this.SendFrameContext(ctx, f, protocol.TradeCache{
+		Date:    date,
+		Code:    code,
+		Decimal: this.decimal(code),
 	})
 	if err != nil {
 		return nil, err
@@ -451,8 +841,13 @@ func (this *Client) GetHistoryTradeFull(code string, w *Workday) (protocol.Trade
 
 // GetHistoryTradeBefore 获取上市至今的分时成交
 func (this *Client) GetHistoryTradeBefore(code string, w *Workday, before time.Time) (protocol.Trades, error) {
+	return this.GetHistoryTradeBeforeContext(context.Background(), code, w, before)
+}
+
+// GetHistoryTradeBeforeContext 同GetHistoryTradeBefore,支持通过上下文取消
+func (this *Client) GetHistoryTradeBeforeContext(ctx context.Context, code string, w *Workday, before time.Time) (protocol.Trades, error) {
 	ls := protocol.Trades(nil)
-	resp, err := this.GetKlineMonthAll(code)
+	resp, err := this.GetKlineAllContext(ctx, protocol.TypeKlineMonth, code)
 	if err != nil {
 		return nil, err
 	}
@@ -463,8 +858,8 @@ func (this *Client) GetHistoryTradeBefore(code string, w *Workday, before time.T
 	var res *protocol.TradeResp
 	w.Range(start, before, func(t time.Time) bool {
 		for i := 0; i < 3; i++ {
-			res, err = this.GetHistoryTradeDay(t.Format("20060102"), code)
-			if err == nil {
+			res, err = this.GetHistoryMinuteTradeDayContext(ctx, t.Format("20060102"), code)
+			if err == nil || ctx.Err() != nil {
 				break
 			}
 		}
@@ -485,10 +880,18 @@ func (this *Client) GetHistoryTradeDay(date, code string) (*protocol.TradeResp,
 // GetHistoryMinuteTradeDay 获取历史某天分时全部交易,通过多次请求来拼接,只能获取昨天及之前的数据
 // 历史数据只能查到20000609
 func (this *Client) GetHistoryMinuteTradeDay(date, code string) (*protocol.TradeResp, error) {
+	return this.GetHistoryMinuteTradeDayContext(context.Background(), date, code)
+}
+
+// GetHistoryMinuteTradeDayContext 同GetHistoryMinuteTradeDay,支持通过上下文取消
+func (this *Client) GetHistoryMinuteTradeDayContext(ctx context.Context, date, code string) (*protocol.TradeResp, error) {
 	resp := &protocol.TradeResp{}
 	size := uint16(2000)
 	for start := uint16(0); ; start += size {
-		r, err := this.GetHistoryMinuteTrade(date, code, start, size)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetHistoryMinuteTradeContext(ctx, date, code, start, size)
 		if err != nil {
 			return nil, err
 		}
@@ -501,6 +904,225 @@ func (this *Client) GetHistoryMinuteTradeDay(date, code string) (*protocol.Trade
 	return resp, nil
 }
 
+// GetXdXr 获取除权除息信息,包括分红,送转股,配股和股本变动
+func (this *Client) GetXdXr(code string) (*protocol.XdXrResp, error) {
+	return this.GetXdXrContext(context.Background(), code)
+}
+
+// GetXdXrContext 同GetXdXr,支持通过上下文取消
+func (this *Client) GetXdXrContext(ctx context.Context, code string) (*protocol.XdXrResp, error) {
+	code = this.addExchange(code)
+	f, err := protocol.MXdXr.Frame(code)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.XdXrResp), nil
+}
+
+// GetFinance 获取财务信息,包括股本,资产,利润,上市日期,行业和省份等
+func (this *Client) GetFinance(code string) (*protocol.Finance, error) {
+	return this.GetFinanceContext(context.Background(), code)
+}
+
+// GetFinanceContext 同GetFinance,支持通过上下文取消
+func (this *Client) GetFinanceContext(ctx context.Context, code string) (*protocol.Finance, error) {
+	code = this.addExchange(code)
+	f, err := protocol.MFinance.Frame(code)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.Finance), nil
+}
+
+// GetCompanyCategories 获取F10公司信息目录,例如最新提示,公司概况,股东研究等
+func (this *Client) GetCompanyCategories(code string) (*protocol.CompanyCategoryResp, error) {
+	return this.GetCompanyCategoriesContext(context.Background(), code)
+}
+
+// GetCompanyCategoriesContext 同GetCompanyCategories,支持通过上下文取消
+func (this *Client) GetCompanyCategoriesContext(ctx context.Context, code string) (*protocol.CompanyCategoryResp, error) {
+	code = this.addExchange(code)
+	f, err := protocol.MCompanyCategory.Frame(code)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.CompanyCategoryResp), nil
+}
+
+// GetCompanyContent 获取F10公司信息内容,文件名,起始位置和长度从GetCompanyCategories获取
+func (this *Client) GetCompanyContent(code, file string, offset, length uint32) (*protocol.CompanyContentResp, error) {
+	return this.GetCompanyContentContext(context.Background(), code, file, offset, length)
+}
+
+// GetCompanyContentContext 同GetCompanyContent,支持通过上下文取消
+func (this *Client) GetCompanyContentContext(ctx context.Context, code, file string, offset, length uint32) (*protocol.CompanyContentResp, error) {
+	code = this.addExchange(code)
+	f, err := protocol.MCompanyContent.Frame(code, file, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.CompanyContentResp), nil
+}
+
+// GetCompanyContentByCategory 获取F10公司信息目录对应的内容
+func (this *Client) GetCompanyContentByCategory(code string, category *protocol.CompanyCategory) (*protocol.CompanyContentResp, error) {
+	return this.GetCompanyContent(code, category.Filename, category.Start, category.Length)
+}
+
+// GetBlockMeta 获取板块文件信息,例如文件大小
+func (this *Client) GetBlockMeta(filename string) (*protocol.BlockMetaResp, error) {
+	return this.GetBlockMetaContext(context.Background(), filename)
+}
+
+// GetBlockMetaContext 同GetBlockMeta,支持通过上下文取消
+func (this *Client) GetBlockMetaContext(ctx context.Context, filename string) (*protocol.BlockMetaResp, error) {
+	f, err := protocol.MBlockMeta.Frame(filename)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.BlockMetaResp), nil
+}
+
+// GetBlockFile 分段获取板块文件内容,单次最多获取30000字节
+func (this *Client) GetBlockFile(filename string, start, size uint32) (*protocol.BlockResp, error) {
+	return this.GetBlockFileContext(context.Background(), filename, start, size)
+}
+
+// GetBlockFileContext 同GetBlockFile,支持通过上下文取消
+func (this *Client) GetBlockFileContext(ctx context.Context, filename string, start, size uint32) (*protocol.BlockResp, error) {
+	f, err := protocol.MBlock.Frame(filename, start, size)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.BlockResp), nil
+}
+
+// GetBlockFileAll 通过多次请求的方式下载完整的板块文件
+func (this *Client) GetBlockFileAll(filename string) ([]byte, error) {
+	return this.GetBlockFileAllContext(context.Background(), filename)
+}
+
+// GetBlockFileAllContext 同GetBlockFileAll,支持通过上下文取消
+func (this *Client) GetBlockFileAllContext(ctx context.Context, filename string) ([]byte, error) {
+	meta, err := this.GetBlockMetaContext(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return this.getBlockFileAll(ctx, filename, meta)
+}
+
+// getBlockFileAll 按已经获取的文件信息分段下载,避免重复请求文件信息
+func (this *Client) getBlockFileAll(ctx context.Context, filename string, meta *protocol.BlockMetaResp) ([]byte, error) {
+	data := make([]byte, 0, meta.Size)
+	size := uint32(protocol.BlockChunkSize)
+	for start := uint32(0); start < meta.Size; start += size {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := this.GetBlockFileContext(ctx, filename, start, size)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, resp.Data...)
+		if len(resp.Data) == 0 {
+			break
+		}
+	}
+	return data, nil
+}
+
+// GetBlocks 下载并解析板块文件,例protocol.BlockFileConcept
+func (this *Client) GetBlocks(filename string) ([]*protocol.Block, error) {
+	return this.GetBlocksContext(context.Background(), filename)
+}
+
+// GetBlocksContext 同GetBlocks,支持通过上下文取消
+func (this *Client) GetBlocksContext(ctx context.Context, filename string) ([]*protocol.Block, error) {
+	bs, err := this.GetBlockFileAllContext(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.DecodeBlockFile(bs)
+}
+
+// GetKlineDayQfqAll 获取前复权的日k线全部数据,通过除权除息信息在本地计算
+func (this *Client) GetKlineDayQfqAll(code string) (*protocol.KlineResp, error) {
+	return this.GetKlineAdjustAll(protocol.TypeKlineDay, code, true)
+}
+
+// GetKlineDayHfqAll 获取后复权的日k线全部数据,通过除权除息信息在本地计算
+func (this *Client) GetKlineDayHfqAll(code string) (*protocol.KlineResp, error) {
+	return this.GetKlineAdjustAll(protocol.TypeKlineDay, code, false)
+}
+
+// GetKlineAdjustAll 获取复权的k线全部数据,qfq为true是前复权,否则是后复权,分钟k线也适用
+func (this *Client) GetKlineAdjustAll(Type uint8, code string, qfq bool) (*protocol.KlineResp, error) {
+	return this.GetKlineAdjustAllContext(context.Background(), Type, code, qfq)
+}
+
+// GetKlineAdjustAllContext 同GetKlineAdjustAll,支持通过上下文取消
+func (this *Client) GetKlineAdjustAllContext(ctx context.Context, Type uint8, code string, qfq bool) (*protocol.KlineResp, error) {
+	resp, err := this.GetKlineAllContext(ctx, Type, code)
+	if err != nil {
+		return nil, err
+	}
+	xdxr, err := this.GetXdXrContext(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if qfq {
+		resp.List = protocol.Klines(resp.List).Qfq(xdxr.List)
+	} else {
+		resp.List = protocol.Klines(resp.List).Hfq(xdxr.List)
+	}
+	return resp, nil
+}
+
+// GetAuction 获取当日集合竞价数据(9:15-9:25),包括虚拟匹配价格,匹配量和未匹配量
+func (this *Client) GetAuction(code string) (*protocol.AuctionResp, error) {
+	return this.GetAuctionContext(context.Background(), code)
+}
+
+// GetAuctionContext 同GetAuction,支持通过上下文取消
+func (this *Client) GetAuctionContext(ctx context.Context, code string) (*protocol.AuctionResp, error) {
+	code = this.addExchange(code)
+	f, err := protocol.MAuction.Frame(code)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f, protocol.AuctionCache{
+		Date: time.Now().In(protocol.LocationCST).Format("20060102"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.AuctionResp), nil
+}
+
 /*
 
 
@@ -509,12 +1131,20 @@ func (this *Client) GetHistoryMinuteTradeDay(date, code string) (*protocol.Trade
 
 // GetIndex 获取指数,接口是和k线一样的,但是解析不知道怎么区分(解析方式不一致),所以加一个方法
 func (this *Client) GetIndex(Type uint8, code string, start, count uint16) (*protocol.KlineResp, error) {
-	code = protocol.AddPrefix(code)
+	return this.GetIndexContext(context.Background(), Type, code, start, count)
+}
+
+// GetIndexContext 同GetIndex,支持通过上下文取消
+func (this *Client) GetIndexContext(ctx context.Context, Type uint8, code string, start, count uint16) (*protocol.KlineResp, error) {
+	//只有6位代码时按指数处理,例000001是上证指数
+	if symbol, err := protocol.ParseIndexSymbol(code); err == nil {
+		code = symbol.String()
+	}
 	f, err := protocol.MKline.Frame(Type, code, start, count)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f, protocol.KlineCache{Type: Type, Kind: protocol.KindIndex})
+	result, err := this.SendFrameContext(ctx, f, protocol.KlineCache{Type: Type, Kind: protocol.KindIndex, Code: code, Decimal: this.decimal(code)})
 	if err != nil {
 		return nil, err
 	}
@@ -523,11 +1153,19 @@ func (this *Client) GetIndex(Type uint8, code string, start, count uint16) (*pro
 
 // GetIndexUntil 获取指数k线数据，通过多次请求来拼接,直到满足func返回true
 func (this *Client) GetIndexUntil(Type uint8, code string, f func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
+	return this.GetIndexUntilContext(context.Background(), Type, code, f)
+}
+
+// GetIndexUntilContext 同GetIndexUntil,支持通过上下文取消
+func (this *Client) GetIndexUntilContext(ctx context.Context, Type uint8, code string, f func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
 	resp := &protocol.KlineResp{}
 	size := uint16(800)
 	var last *protocol.Kline
 	for start := uint16(0); ; start += size {
-		r, err := this.GetIndex(Type, code, start, size)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetIndexContext(ctx, Type, code, start, size)
 		if err != nil {
 			return nil, err
 		}
@@ -555,7 +1193,12 @@ func (this *Client) GetIndexUntil(Type uint8, code string, f func(k *protocol.Kl
 
 // GetIndexAll 获取全部k线数据
 func (this *Client) GetIndexAll(Type uint8, code string) (*protocol.KlineResp, error) {
-	return this.GetIndexUntil(Type, code, func(k *protocol.Kline) bool { return false })
+	return this.GetIndexAllContext(context.Background(), Type, code)
+}
+
+// GetIndexAllContext 同GetIndexAll,支持通过上下文取消
+func (this *Client) GetIndexAllContext(ctx context.Context, Type uint8, code string) (*protocol.KlineResp, error) {
+	return this.GetIndexUntilContext(ctx, Type, code, func(k *protocol.Kline) bool { return false })
 }
 
 func (this *Client) GetIndexDay(code string, start, count uint16) (*protocol.KlineResp, error) {
@@ -593,12 +1236,17 @@ func (this *Client) GetIndexYearAll(code string) (*protocol.KlineResp, error) {
 
 // GetKline 获取k线数据,推荐收盘之后获取,否则会获取到当天的数据
 func (this *Client) GetKline(Type uint8, code string, start, count uint16) (*protocol.KlineResp, error) {
-	code = protocol.AddPrefix(code)
+	return this.GetKlineContext(context.Background(), Type, code, start, count)
+}
+
+// GetKlineContext 同GetKline,支持通过上下文取消
+func (this *Client) GetKlineContext(ctx context.Context, Type uint8, code string, start, count uint16) (*protocol.KlineResp, error) {
+	code = this.addExchange(code)
 	f, err := protocol.MKline.Frame(Type, code, start, count)
 	if err != nil {
 		return nil, err
 	}
-	result, err := this.SendFrame(f, protocol.KlineCache{Type: Type, Kind: protocol.KindStock})
+	result, err := this.SendFrameContext(ctx, f, protocol.KlineCache{Type: Type, Kind: protocol.KindStock, Code: code, Decimal: this.decimal(code)})
 	if err != nil {
 		return nil, err
 	}
@@ -607,11 +1255,19 @@ func (this *Client) GetKline(Type uint8, code string, start, count uint16) (*pro
 
 // GetKlineUntil 获取k线数据，通过多次请求来拼接,直到满足func返回true
 func (this *Client) GetKlineUntil(Type uint8, code string, f func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
+	return this.GetKlineUntilContext(context.Background(), Type, code, f)
+}
+
+// GetKlineUntilContext 同GetKlineUntil,支持通过上下文取消
+func (this *Client) GetKlineUntilContext(ctx context.Context, Type uint8, code string, f func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
 	resp := &protocol.KlineResp{}
 	size := uint16(800)
 	var last *protocol.Kline
 	for start := uint16(0); ; start += size {
-		r, err := this.GetKline(Type, code, start, size)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetKlineContext(ctx, Type, code, start, size)
 		if err != nil {
 			return nil, err
 		}
@@ -639,7 +1295,12 @@ func (this *Client) GetKlineUntil(Type uint8, code string, f func(k *protocol.Kl
 
 // GetKlineAll 获取全部k线数据
 func (this *Client) GetKlineAll(Type uint8, code string) (*protocol.KlineResp, error) {
-	return this.GetKlineUntil(Type, code, func(k *protocol.Kline) bool { return false })
+	return this.GetKlineAllContext(context.Background(), Type, code)
+}
+
+// GetKlineAllContext 同GetKlineAll,支持通过上下文取消
+func (this *Client) GetKlineAllContext(ctx context.Context, Type uint8, code string) (*protocol.KlineResp, error) {
+	return this.GetKlineUntilContext(ctx, Type, code, func(k *protocol.Kline) bool { return false })
 }
 
 // GetKlineMinute 获取一分钟k线数据,每次最多800条,最多只能获取24000条数据
@@ -0,0 +1,367 @@
+package tdx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/injoyai/base/maps"
+	"github.com/injoyai/base/maps/wait"
+	"github.com/injoyai/conv"
+	"github.com/injoyai/ios"
+	"github.com/injoyai/ios/client"
+	"github.com/injoyai/ios/module/tcp"
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx/protocol"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// NewExTCPDial 扩展行情的连接方式,默认端口7727
+func NewExTCPDial(addr string) ios.DialFunc {
+	if !strings.Contains(addr, ":") {
+		addr += ":7727"
+	}
+	return tcp.NewDial(addr)
+}
+
+// DialEx 与扩展行情服务器建立连接
+func DialEx(addr string, op ...client.Option) (cli *ExClient, err error) {
+	return DialExWith(NewExTCPDial(addr), op...)
+}
+
+// DialExHostsRange 遍历设置的扩展行情服务地址进行连接,成功则结束遍历
+func DialExHostsRange(hosts []string, op ...client.Option) (cli *ExClient, err error) {
+	if len(hosts) == 0 {
+		hosts = ExHosts
+	}
+	return DialExWith(NewRangeDial(hosts), op...)
+}
+
+// DialExWith 与扩展行情服务器建立连接
+func DialExWith(dial ios.DialFunc, op ...client.Option) (cli *ExClient, err error) {
+
+	cli = &ExClient{
+		Wait:    wait.New(time.Second * 2),
+		m:       maps.NewSafe(),
+		timeout: time.Second * 2,
+	}
+
+	cli.Client, err = client.Dial(dial, func(c *client.Client) {
+		c.Logger.SetLevel(LevelInfo)                   //设置日志级别,通讯数据通过WithLevel和WithHEX设置
+		c.Event.OnReadFrom = protocol.ReadFrom         //分包,响应和标准行情一致
+		c.Event.OnDealMessage = cli.handlerDealMessage //解析数据并处理
+		setEvent(c, op, func(c *client.Client) error {
+			cli.state.connect()
+			//扩展行情没有单独的心跳,用获取合约数量代替
+			c.GoTimerWriter(30*time.Second, func(w ios.MoreWriter) error {
+				bs := protocol.MExCount.Frame().BytesEx()
+				_, err := cli.state.write(w, bs)
+				return err
+			})
+			f := protocol.MExConnect.Frame()
+			if _, err := cli.state.write(c, f.BytesEx()); err != nil {
+				c.Close()
+			}
+			return nil
+		}, func(c *client.Client, err error) {
+			cli.state.disconnect(cli.Wait)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go cli.Client.Run()
+
+	return cli, err
+}
+
+// ExClient 扩展行情客户端,期货,港股,期权等
+type ExClient struct {
+	*client.Client               //客户端实例
+	Wait           *wait.Entity  //异步回调,设置超时时间,超时则返回错误
+	m              *maps.Safe    //有部分解析需要用到请求参数,返回数据获取不到,固请求的时候缓存下
+	msgID          uint32        //消息id,使用SendFrame自动累加
+	timeout        time.Duration //等待响应的超时时间,通过SetTimeout设置
+	state          connState     //连接状态和等待响应的请求
+}
+
+// handlerDealMessage 处理服务器响应的数据
+func (this *ExClient) handlerDealMessage(c *client.Client, msg ios.Acker) {
+
+	var f *protocol.Response
+	defer func() {
+		if e := recover(); e != nil {
+			logs.Err(e)
+			debug.PrintStack()
+			if f != nil {
+				//解析异常也通知等待的请求,避免一直等到超时
+				this.Wait.Done(conv.String(f.MsgID), nil, &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: fmt.Errorf("%v", e)})
+			}
+		}
+	}()
+
+	f, err := protocol.Decode(msg.Payload())
+	if err != nil {
+		logs.Err(err)
+		if f != nil {
+			//帧头完整,通知等待的请求
+			this.m.Del(conv.String(f.MsgID))
+			if !errors.Is(err, protocol.ErrServerRejected) {
+				err = &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: err}
+			}
+			this.Wait.Done(conv.String(f.MsgID), nil, err)
+		}
+		return
+	}
+
+	val, _ := this.m.GetAndDel(conv.String(f.MsgID))
+
+	var resp any
+	switch f.Type {
+
+	case protocol.TypeExConnect:
+
+	case protocol.TypeExCount:
+		resp, err = protocol.MExCount.Decode(f.Data)
+
+	case protocol.TypeExMarket:
+		resp, err = protocol.MExMarket.Decode(f.Data)
+
+	case protocol.TypeExInstrument:
+		resp, err = protocol.MExInstrument.Decode(f.Data)
+
+	case protocol.TypeExQuote:
+		resp, err = protocol.MExQuote.Decode(f.Data)
+
+	case protocol.TypeExKline:
+		resp, err = decodeCache(val, f.Data, protocol.MExKline.Decode)
+
+	case protocol.TypeExTrade:
+		resp, err = decodeCache(val, f.Data, protocol.MExTrade.Decode)
+
+	default:
+		err = fmt.Errorf("通讯类型未解析:0x%X", f.Type)
+
+	}
+
+	if err != nil {
+		logs.Err(err)
+		this.Wait.Done(conv.String(f.MsgID), nil, &protocol.DecodeError{Type: f.Type, Frame: msg.Payload(), Err: err})
+		return
+	}
+
+	this.Wait.Done(conv.String(f.MsgID), resp)
+
+}
+
+// Closed 连接是否已断开,开启重连时重连成功后恢复
+func (this *ExClient) Closed() bool {
+	return this.state.closed()
+}
+
+// SetTimeout 设置超时时间
+func (this *ExClient) SetTimeout(t time.Duration) {
+	this.timeout = t
+	this.Wait.SetTimeout(t)
+}
+
+// SendFrame 发送数据,并等待响应
+func (this *ExClient) SendFrame(f *protocol.Frame, cache ...any) (any, error) {
+	return this.SendFrameContext(context.Background(), f, cache...)
+}
+
+// SendFrameContext 发送数据,并等待响应,上下文取消时立即释放等待并返回上下文的错误
+func (this *ExClient) SendFrameContext(ctx context.Context, f *protocol.Frame, cache ...any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.MsgID = atomic.AddUint32(&this.msgID, 1)
+	key := conv.String(f.MsgID)
+	if len(cache) > 0 {
+		this.m.Set(key, cache[0])
+	}
+	ch := register(this.Wait, key, this.timeout)
+	this.state.add(key)
+	defer this.state.del(key)
+	if this.Closed() {
+		this.Wait.Done(key, nil, ErrClosed)
+		this.m.Del(key)
+		return nil, ErrClosed
+	}
+	if _, err := this.state.write(this.Client, f.BytesEx()); err != nil {
+		this.Wait.Done(key, nil, err)
+		this.m.Del(key)
+		if this.Closed() {
+			return nil, ErrClosed
+		}
+		return nil, err
+	}
+	return waitContext(ctx, this.Wait, key, ch, this.timeout, func() { this.m.Del(key) })
+}
+
+// GetMarkets 获取扩展行情的市场列表
+func (this *ExClient) GetMarkets() (*protocol.ExMarketResp, error) {
+	return this.GetMarketsContext(context.Background())
+}
+
+// GetMarketsContext 同GetMarkets,支持通过上下文取消
+func (this *ExClient) GetMarketsContext(ctx context.Context) (*protocol.ExMarketResp, error) {
+	result, err := this.SendFrameContext(ctx, protocol.MExMarket.Frame())
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExMarketResp), nil
+}
+
+// GetCount 获取扩展行情的合约数量
+func (this *ExClient) GetCount() (*protocol.ExCountResp, error) {
+	return this.GetCountContext(context.Background())
+}
+
+// GetCountContext 同GetCount,支持通过上下文取消
+func (this *ExClient) GetCountContext(ctx context.Context) (*protocol.ExCountResp, error) {
+	result, err := this.SendFrameContext(ctx, protocol.MExCount.Frame())
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExCountResp), nil
+}
+
+// GetInstrument 获取指定范围的合约列表,全部市场混在一起
+func (this *ExClient) GetInstrument(start uint32, count uint16) (*protocol.ExInstrumentResp, error) {
+	return this.GetInstrumentContext(context.Background(), start, count)
+}
+
+// GetInstrumentContext 同GetInstrument,支持通过上下文取消
+func (this *ExClient) GetInstrumentContext(ctx context.Context, start uint32, count uint16) (*protocol.ExInstrumentResp, error) {
+	result, err := this.SendFrameContext(ctx, protocol.MExInstrument.Frame(start, count))
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExInstrumentResp), nil
+}
+
+// GetInstrumentAll 通过多次请求的方式获取全部合约,可以按市场筛选,不传则是全部市场
+func (this *ExClient) GetInstrumentAll(markets ...protocol.ExMarket) (*protocol.ExInstrumentResp, error) {
+	return this.GetInstrumentAllContext(context.Background(), markets...)
+}
+
+// GetInstrumentAllContext 同GetInstrumentAll,支持通过上下文取消
+func (this *ExClient) GetInstrumentAllContext(ctx context.Context, markets ...protocol.ExMarket) (*protocol.ExInstrumentResp, error) {
+	resp := &protocol.ExInstrumentResp{}
+	size := uint16(500)
+	for start := uint32(0); ; start += uint32(size) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetInstrumentContext(ctx, start, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range r.List {
+			if len(markets) == 0 || exMarketIn(v.Market, markets) {
+				resp.List = append(resp.List, v)
+			}
+		}
+		if r.Count < size {
+			break
+		}
+	}
+	resp.Count = uint16(len(resp.List))
+	return resp, nil
+}
+
+func exMarketIn(market protocol.ExMarket, markets []protocol.ExMarket) bool {
+	for _, v := range markets {
+		if v == market {
+			return true
+		}
+	}
+	return false
+}
+
+// GetQuote 获取合约的盘口,例(protocol.ExMarketCFFEX,"IF2412")
+func (this *ExClient) GetQuote(market protocol.ExMarket, code string) (*protocol.ExQuote, error) {
+	return this.GetQuoteContext(context.Background(), market, code)
+}
+
+// GetQuoteContext 同GetQuote,支持通过上下文取消
+func (this *ExClient) GetQuoteContext(ctx context.Context, market protocol.ExMarket, code string) (*protocol.ExQuote, error) {
+	f, err := protocol.MExQuote.Frame(market, code)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExQuote), nil
+}
+
+// GetKline 获取合约的k线数据,类型和标准行情一致,例protocol.TypeKlineDay,每次最多800条
+func (this *ExClient) GetKline(Type uint8, market protocol.ExMarket, code string, start uint32, count uint16) (*protocol.ExKlineResp, error) {
+	return this.GetKlineContext(context.Background(), Type, market, code, start, count)
+}
+
+// GetKlineContext 同GetKline,支持通过上下文取消
+func (this *ExClient) GetKlineContext(ctx context.Context, Type uint8, market protocol.ExMarket, code string, start uint32, count uint16) (*protocol.ExKlineResp, error) {
+	f, err := protocol.MExKline.Frame(Type, market, code, start, count)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f, protocol.KlineCache{Type: Type})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExKlineResp), nil
+}
+
+// GetKlineAll 获取合约的k线全部数据,通过多次请求来拼接
+func (this *ExClient) GetKlineAll(Type uint8, market protocol.ExMarket, code string) (*protocol.ExKlineResp, error) {
+	return this.GetKlineAllContext(context.Background(), Type, market, code)
+}
+
+// GetKlineAllContext 同GetKlineAll,支持通过上下文取消
+func (this *ExClient) GetKlineAllContext(ctx context.Context, Type uint8, market protocol.ExMarket, code string) (*protocol.ExKlineResp, error) {
+	resp := &protocol.ExKlineResp{}
+	size := uint16(800)
+	for start := uint32(0); ; start += uint32(size) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := this.GetKlineContext(ctx, Type, market, code, start, size)
+		if err != nil {
+			return nil, err
+		}
+		resp.Count += r.Count
+		resp.List = append(r.List, resp.List...)
+		if r.Count < size {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// GetTrade 获取合约当日的分笔成交
+func (this *ExClient) GetTrade(market protocol.ExMarket, code string, start uint32, count uint16) (*protocol.ExTradeResp, error) {
+	return this.GetTradeContext(context.Background(), market, code, start, count)
+}
+
+// GetTradeContext 同GetTrade,支持通过上下文取消
+func (this *ExClient) GetTradeContext(ctx context.Context, market protocol.ExMarket, code string, start uint32, count uint16) (*protocol.ExTradeResp, error) {
+	f, err := protocol.MExTrade.Frame(market, code, start, count)
+	if err != nil {
+		return nil, err
+	}
+	result, err := this.SendFrameContext(ctx, f, protocol.TradeCache{
+		Date: time.Now().In(protocol.LocationCST).Format("20060102"),
+		Code: code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ExTradeResp), nil
+}
@@ -0,0 +1,231 @@
+package tdx_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/injoyai/ios/client"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+// dial 连接模拟服务器,测试结束时关闭
+func dial(t *testing.T) (*tdxtest.Server, *tdx.Client) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//北交所代码不从官网获取,测试结果不受网络影响
+	c, err := tdx.Dial(s.Addr(), tdx.WithDebug(false), tdx.WithBjCodes(func() ([]*tdx.BjCode, error) { return nil, nil }))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return s, c
+}
+
+// indexResolver 把6位代码都解析成上海的代码,例000001是上证指数
+type indexResolver struct{}
+
+func (indexResolver) AddExchange(code string) string {
+	if len(code) == 6 {
+		return "sh" + code
+	}
+	return code
+}
+
+func (indexResolver) Get(code string) *tdx.CodeModel {
+	return nil
+}
+
+func TestClient_SetCodeResolver(t *testing.T) {
+	s, c := dial(t)
+
+	//债券无法按规则推断交易所
+	if _, err := c.GetQuote("113050"); !errors.Is(err, tdx.ErrInvalidCode) {
+		t.Errorf("预期ErrInvalidCode,得到%v", err)
+	}
+
+	codes := []string{"000001", "510300"}
+
+	c.SetCodeResolver(indexResolver{})
+	quotes, err := c.GetQuote(codes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes[0] != "000001" || codes[1] != "510300" {
+		t.Errorf("调用方的参数被修改: %v", codes)
+	}
+	if want := s.Fixture.Quotes["sh000001"]; quotes[0].K != want.K {
+		t.Errorf("预期上证指数%v,得到%v", want.K, quotes[0].K)
+	}
+	if want := s.Fixture.Quotes["sh510300"]; quotes[1].K != want.K {
+		t.Errorf("预期沪深300ETF%v,得到%v", want.K, quotes[1].K)
+	}
+}
+
+func TestDialWith_Options(t *testing.T) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var heart, count int32
+	s.Handle(protocol.TypeHeart, func(f *protocol.Frame) ([]byte, error) {
+		atomic.AddInt32(&heart, 1)
+		return nil, nil
+	})
+	s.Handle(protocol.TypeCount, func(f *protocol.Frame) ([]byte, error) {
+		atomic.AddInt32(&count, 1)
+		<-time.After(time.Millisecond * 300)
+		return nil, errors.New("慢响应")
+	})
+
+	connected := make(chan *tdx.Client, 1)
+	disconnected := make(chan error, 1)
+	c, err := tdx.Dial(s.Addr(),
+		tdx.WithDebug(false),
+		tdx.WithHeartbeat(time.Millisecond*50),
+		tdx.WithTimeout(time.Millisecond*100),
+		tdx.WithTypeTimeout(protocol.TypeCount, time.Second),
+		tdx.WithMaxInflight(1),
+		tdx.WithOnConnect(func(c *tdx.Client) { connected <- c }),
+		tdx.WithOnDisconnect(func(c *tdx.Client, err error) { disconnected <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-connected:
+		if v != c {
+			t.Fatal("预期回调的是同一个客户端")
+		}
+	default:
+		t.Fatal("预期执行连接回调")
+	}
+
+	//按消息类型的超时时间,慢响应不会超时
+	go func() {
+		if _, err := c.GetCount(protocol.ExchangeSH); !errors.Is(err, tdx.ErrServerRejected) {
+			t.Errorf("预期服务器拒绝,得到%v", err)
+		}
+	}()
+	<-time.After(time.Millisecond * 50)
+
+	//超过最大请求数量的等待,没有发送
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := c.GetCountContext(ctx, protocol.ExchangeSH); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("预期等待到超时,得到%v", err)
+	}
+	<-time.After(time.Millisecond * 300)
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Fatalf("预期只发送1个请求,得到%d", n)
+	}
+	if n := atomic.LoadInt32(&heart); n < 2 {
+		t.Fatalf("预期按间隔发送心跳,得到%d", n)
+	}
+
+	//取消后使用默认的超时时间
+	c.SetTypeTimeout(protocol.TypeCount, 0)
+	if _, err := c.GetCount(protocol.ExchangeSH); !errors.Is(err, tdx.ErrTimeout) {
+		t.Fatalf("预期超时,得到%v", err)
+	}
+
+	c.CloseAll()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("预期执行断开回调")
+	}
+}
+
+func TestClient_Disconnect(t *testing.T) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	received := make(chan struct{}, 1)
+	s.Handle(protocol.TypeCount, func(f *protocol.Frame) ([]byte, error) {
+		received <- struct{}{}
+		<-release
+		return nil, errors.New("已断开")
+	})
+	c, err := tdx.Dial(s.Addr(), tdx.WithDebug(false), tdx.WithTimeout(time.Second*10))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Closed() {
+		t.Fatal("预期已连接")
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.GetCount(protocol.ExchangeSH)
+		result <- err
+	}()
+	<-received
+	//服务器断开连接,等待中的请求立即返回ErrClosed,不用等到超时
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case err := <-result:
+		if !errors.Is(err, tdx.ErrClosed) {
+			t.Fatalf("预期ErrClosed,得到%v", err)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("断开后等待中的请求没有返回")
+	}
+	close(release)
+	<-closed
+	if !c.Closed() {
+		t.Fatal("预期已断开")
+	}
+	if _, err := c.GetCount(protocol.ExchangeSH); !errors.Is(err, tdx.ErrClosed) {
+		t.Fatalf("预期ErrClosed,得到%v", err)
+	}
+}
+
+func TestDialWith_Event(t *testing.T) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	//选项中设置的连接事件不会替换内置的握手和连接状态
+	connected := make(chan struct{}, 1)
+	c, err := tdx.Dial(s.Addr(), tdx.WithDebug(false), client.WithConnect(func(c *client.Client) error {
+		connected <- struct{}{}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	select {
+	case <-connected:
+	default:
+		t.Fatal("预期执行选项中的连接事件")
+	}
+	if c.Closed() {
+		t.Fatal("预期已连接")
+	}
+	if _, err := c.GetCount(protocol.ExchangeSH); err != nil {
+		t.Fatal(err)
+	}
+}
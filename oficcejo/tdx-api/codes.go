@@ -7,17 +7,25 @@ import (
 	"github.com/injoyai/logs"
 	"github.com/injoyai/tdx/protocol"
 	"github.com/robfig/cron/v3"
-	"math"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 	"xorm.io/core"
 	"xorm.io/xorm"
 )
 
-// DefaultCodes 增加单例,部分数据需要通过Codes里面的信息计算
+// DefaultCodes 增加单例,方便共享,Client不会读取,需要的话通过Client.SetCodeResolver设置
 var DefaultCodes *Codes
 
+// CodeResolver 代码信息,Client用于补全交易所前缀和获取价格小数位,名称等,*Codes实现了该接口
+type CodeResolver interface {
+	// AddExchange 补全交易所前缀,例510300返回sh510300,无法确定时原样返回
+	AddExchange(code string) string
+	// Get 获取代码信息,code带交易所前缀,不存在返回nil
+	Get(code string) *CodeModel
+}
+
 func DialCodes(filename string, op ...client.Option) (*Codes, error) {
 	c, err := DialDefault(op...)
 	if err != nil {
@@ -68,6 +76,9 @@ func NewCodes(c *Client, db *xorm.Engine) (*Codes, error) {
 	if err := db.Sync2(new(UpdateModel)); err != nil {
 		return nil, err
 	}
+	if err := db.Sync2(new(CodeHistoryModel)); err != nil {
+		return nil, err
+	}
 
 	update := new(UpdateModel)
 	{ //查询或者插入一条数据
@@ -85,11 +96,11 @@ func NewCodes(c *Client, db *xorm.Engine) (*Codes, error) {
 	cc := &Codes{
 		Client: c,
 		db:     db,
+		task:   cron.New(cron.WithSeconds()),
 	}
 
-	{ //设置定时器,每天早上9点更新数据
-		task := cron.New(cron.WithSeconds())
-		task.AddFunc("10 0 9 * * *", func() {
+	{ //设置定时器,每天早上9点更新数据,不再使用时通过Stop停止
+		cc.task.AddFunc("10 0 9 * * *", func() {
 			for i := 0; i < 3; i++ {
 				err := cc.Update()
 				if err == nil {
@@ -99,7 +110,7 @@ func NewCodes(c *Client, db *xorm.Engine) (*Codes, error) {
 				<-time.After(time.Minute * 5)
 			}
 		})
-		task.Start()
+		cc.task.Start()
 	}
 
 	{ //判断是否更新过,更新过则不更新
@@ -123,17 +134,55 @@ func NewCodes(c *Client, db *xorm.Engine) (*Codes, error) {
 	return cc, cc.Update(true)
 }
 
+// Codes 代码信息,可以并发读写,Update会在后台定时执行,缓存通过方法获取
 type Codes struct {
-	*Client                         //客户端
-	db        *xorm.Engine          //数据库实例
-	Map       map[string]*CodeModel //股票缓存
-	list      []*CodeModel          //列表方式缓存
-	exchanges map[string][]string   //交易所缓存
+	*Client                                                  //客户端
+	db          *xorm.Engine                                 //数据库实例
+	updateMu    sync.Mutex                                   //保证同时只有一个更新
+	mu          sync.RWMutex                                 //保护下面的缓存
+	m           map[string]*CodeModel                        //股票缓存
+	list        []*CodeModel                                 //列表方式缓存
+	exchanges   map[string][]string                          //交易所缓存
+	sectors     []*Sector                                    //板块缓存
+	codeSectors map[string][]*Sector                         //代码所属板块缓存
+	nameSectors map[string][]*Sector                         //板块名称缓存
+	sectorMu    sync.Mutex                                   //保证同时只有一个板块更新,保护blockFiles
+	blockFiles  map[string]*blockFile                        //已下载的板块文件,hash不变时不重复下载
+	search      []*searchEntry                               //搜索索引
+	onChange    []func(added, removed, renamed []*CodeModel) //代码变化的回调
+	task        *cron.Cron                                   //每天定时更新的任务,通过Stop停止
+}
+
+// Stop 停止每天的定时更新,已经开始的更新不受影响,不会关闭客户端和数据库
+func (this *Codes) Stop() {
+	this.task.Stop()
+}
+
+// OnChange 代码变化时回调,added新上市,removed退市,renamed改名(新的信息),在更新的协程中执行,
+// 只有从服务器或者数据库加载后和之前的缓存有差异时才会触发,例如依赖代码的缓存可以在这里失效
+func (this *Codes) OnChange(f func(added, removed, renamed []*CodeModel)) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.onChange = append(this.onChange, f)
+}
+
+// Len 代码数量
+func (this *Codes) Len() int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return len(this.list)
+}
+
+// List 全部代码信息,返回的是副本,CodeModel不要修改
+func (this *Codes) List() []*CodeModel {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return append([]*CodeModel(nil), this.list...)
 }
 
 // GetName 获取股票名称
 func (this *Codes) GetName(code string) string {
-	if v, ok := this.Map[code]; ok {
+	if v := this.Get(code); v != nil {
 		return v.Name
 	}
 	return "未知"
@@ -141,11 +190,20 @@ func (this *Codes) GetName(code string) string {
 
 // GetStocks 获取股票代码,sh6xxx sz0xx sz30xx
 func (this *Codes) GetStocks(limits ...int) []string {
+	return this.filter(protocol.IsStock, limits...)
+}
+
+// GetETFs 获取基金代码,sz159xxx,sh510xxx,sh511xxx
+func (this *Codes) GetETFs(limits ...int) []string {
+	return this.filter(protocol.IsETF, limits...)
+}
+
+func (this *Codes) filter(f func(code string) bool, limits ...int) []string {
 	limit := conv.Default(-1, limits...)
 	ls := []string(nil)
-	for _, m := range this.list {
+	for _, m := range this.List() {
 		code := m.FullCode()
-		if protocol.IsStock(code) {
+		if f(code) {
 			ls = append(ls, code)
 		}
 		if limit > 0 && len(ls) >= limit {
@@ -155,33 +213,59 @@ func (this *Codes) GetStocks(limits ...int) []string {
 	return ls
 }
 
-// GetETFs 获取基金代码,sz159xxx,sh510xxx,sh511xxx
-func (this *Codes) GetETFs(limits ...int) []string {
-	limit := conv.Default(-1, limits...)
-	ls := []string(nil)
-	for _, m := range this.list {
-		code := m.FullCode()
-		if protocol.IsETF(code) {
-			ls = append(ls, code)
-		}
-		if limit > 0 && len(ls) >= limit {
-			break
+// GetByType 获取指定证券类型的代码信息,不指定类型返回全部,例GetByType(protocol.SecurityCBond)
+func (this *Codes) GetByType(types ...protocol.SecurityType) []*CodeModel {
+	m := make(map[protocol.SecurityType]bool, len(types))
+	for _, v := range types {
+		m[v] = true
+	}
+	ls := []*CodeModel(nil)
+	for _, v := range this.List() {
+		if len(types) == 0 || m[v.SecurityType] {
+			ls = append(ls, v)
 		}
 	}
 	return ls
 }
 
+// Get 获取代码信息,支持的格式见protocol.ParseSymbol,不存在返回nil
 func (this *Codes) Get(code string) *CodeModel {
-	return this.Map[code]
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.m[this.addExchange(code)]
 }
 
+// AddExchange 统一成带交易所前缀的格式,支持的格式见protocol.ParseSymbol,
+// 只有6位代码且推断不出来的在代码库中查找,例上海的债券
 func (this *Codes) AddExchange(code string) string {
-	return protocol.AddPrefix(code)
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.addExchange(code)
 }
 
-// Update 更新数据,从服务器或者数据库
+// addExchange 同AddExchange,调用方需要持有读锁
+func (this *Codes) addExchange(code string) string {
+	if s, err := protocol.ParseSymbol(code); err == nil {
+		return s.String()
+	}
+	if len(code) != 6 {
+		return code
+	}
+	for _, exchange := range []protocol.Exchange{protocol.ExchangeSH, protocol.ExchangeSZ, protocol.ExchangeBJ} {
+		if _, ok := this.m[exchange.String()+code]; ok {
+			return exchange.String() + code
+		}
+	}
+	return code
+}
+
+// Update 更新数据,从服务器或者数据库,多次调用会排队执行
 func (this *Codes) Update(byDB ...bool) error {
-	codes, err := this.GetCodes(len(byDB) > 0 && byDB[0])
+	this.updateMu.Lock()
+	defer this.updateMu.Unlock()
+
+	fromDB := len(byDB) > 0 && byDB[0]
+	codes, err := this.getCodes(fromDB)
 	if err != nil {
 		return err
 	}
@@ -191,16 +275,71 @@ func (this *Codes) Update(byDB ...bool) error {
 		codeMap[code.Exchange+code.Code] = code
 		exchanges[code.Code] = append(exchanges[code.Code], code.Exchange)
 	}
-	this.Map = codeMap
+
+	index := newSearchIndex(codes)
+
+	this.mu.Lock()
+	old := this.m
+	this.m = codeMap
 	this.list = codes
 	this.exchanges = exchanges
+	this.search = index
+	onChange := this.onChange
+	this.mu.Unlock()
+
+	//板块信息依赖代码的交易所,板块文件没有变化时只请求文件信息,失败不影响代码的更新,
+	//从数据库加载时不请求服务器,需要的话手动调用UpdateSectors
+	if !fromDB {
+		if err := this.UpdateSectors(); err != nil {
+			logs.Err(err)
+		}
+	}
+
+	//通知代码的变化,第一次加载不通知
+	if old != nil && len(onChange) > 0 {
+		added, removed, renamed := diffCodes(old, codeMap)
+		if len(added)+len(removed)+len(renamed) > 0 {
+			for _, f := range onChange {
+				f(added, removed, renamed)
+			}
+		}
+	}
+
 	//更新时间
 	_, err = this.db.Where("`Key`=?", "codes").Update(&UpdateModel{Time: time.Now().Unix()})
 	return err
 }
 
-// GetCodes 更新股票并返回结果
+// diffCodes 对比新旧的代码,renamed返回新的信息
+func diffCodes(old, new map[string]*CodeModel) (added, removed, renamed []*CodeModel) {
+	for k, v := range new {
+		o, ok := old[k]
+		switch {
+		case !ok:
+			added = append(added, v)
+		case o.Name != v.Name:
+			renamed = append(renamed, v)
+		}
+	}
+	for k, v := range old {
+		if _, ok := new[k]; !ok {
+			removed = append(removed, v)
+		}
+	}
+	return
+}
+
+// GetCodes 获取代码,byDatabase为false时从服务器更新数据库并返回结果,不会更新缓存,缓存需要调用Update
 func (this *Codes) GetCodes(byDatabase bool) ([]*CodeModel, error) {
+	if !byDatabase {
+		this.updateMu.Lock()
+		defer this.updateMu.Unlock()
+	}
+	return this.getCodes(byDatabase)
+}
+
+// getCodes 更新股票并返回结果,调用方需要持有updateMu
+func (this *Codes) getCodes(byDatabase bool) ([]*CodeModel, error) {
 
 	if this.Client == nil {
 		return nil, errors.New("client is nil")
@@ -212,52 +351,95 @@ func (this *Codes) GetCodes(byDatabase bool) ([]*CodeModel, error) {
 		return nil, err
 	}
 
+	//之前版本的数据没有证券类型,需要补上
+	fill := []*CodeModel(nil)
+	for _, v := range list {
+		if v.SecurityType == "" {
+			v.SecurityType = protocol.SecurityTypeOf(v.FullCode())
+			fill = append(fill, v)
+		}
+	}
+
 	//如果是从缓存读取,则返回结果
 	if byDatabase {
 		return list, nil
 	}
 
+	//不同交易所的代码会重复,例如sh000001和sz000001
 	mCode := make(map[string]*CodeModel, len(list))
 	for _, v := range list {
-		mCode[v.Code] = v
+		mCode[v.FullCode()] = v
 	}
 
 	//3. 从服务器获取所有股票代码
 	insert := []*CodeModel(nil)
 	update := []*CodeModel(nil)
+	seen := []*CodeModel(nil)
+	fetched := []string(nil)
 	for _, exchange := range []protocol.Exchange{protocol.ExchangeSH, protocol.ExchangeSZ, protocol.ExchangeBJ} {
 		resp, err := this.Client.GetCodeAll(exchange)
-		if err != nil {
+		if err != nil && exchange == protocol.ExchangeBJ {
+			//北交所的代码是从官网爬取的,失败不影响沪深的更新,也不会被记为退市
+			logs.Err(err)
+			continue
+		} else if err != nil {
 			return nil, err
 		}
+		if len(resp.List) > 0 {
+			fetched = append(fetched, exchange.String())
+		}
 		for _, v := range resp.List {
-			if _, ok := mCode[v.Code]; ok {
-				if mCode[v.Code].Name != v.Name {
-					mCode[v.Code].Name = v.Name
+			if old, ok := mCode[exchange.String()+v.Code]; ok {
+				seen = append(seen, old)
+				if old.Name != v.Name {
+					old.Name = v.Name
 					update = append(update, &CodeModel{
-						Name:      v.Name,
-						Code:      v.Code,
-						Exchange:  exchange.String(),
-						Multiple:  v.Multiple,
-						Decimal:   v.Decimal,
-						LastPrice: v.LastPrice,
+						Name:         v.Name,
+						Code:         v.Code,
+						Exchange:     exchange.String(),
+						SecurityType: protocol.SecurityTypeOf(exchange.String() + v.Code),
+						Multiple:     v.Multiple,
+						Decimal:      v.Decimal,
+						LastPrice:    v.LastPrice,
 					})
 				}
 			} else {
 				code := &CodeModel{
-					Name:      v.Name,
-					Code:      v.Code,
-					Exchange:  exchange.String(),
-					Multiple:  v.Multiple,
-					Decimal:   v.Decimal,
-					LastPrice: v.LastPrice,
+					Name:         v.Name,
+					Code:         v.Code,
+					Exchange:     exchange.String(),
+					SecurityType: protocol.SecurityTypeOf(exchange.String() + v.Code),
+					Multiple:     v.Multiple,
+					Decimal:      v.Decimal,
+					LastPrice:    v.LastPrice,
 				}
 				insert = append(insert, code)
 				list = append(list, code)
+				seen = append(seen, code)
 			}
 		}
 	}
 
+	//服务器不再返回的代码已经退市,从代码表删除,历史保留在code_history
+	exist := make(map[string]bool, len(seen))
+	for _, v := range seen {
+		exist[v.FullCode()] = true
+	}
+	isFetched := make(map[string]bool, len(fetched))
+	for _, v := range fetched {
+		isFetched[v] = true
+	}
+	remove := []int64(nil)
+	listed := []*CodeModel(nil)
+	for _, v := range list {
+		if isFetched[v.Exchange] && !exist[v.FullCode()] {
+			remove = append(remove, v.ID)
+			continue
+		}
+		listed = append(listed, v)
+	}
+	list = listed
+
 	switch this.db.Dialect().URI().DBType {
 	case "mysql":
 		// 1️⃣ 清空
@@ -265,7 +447,8 @@ func (this *Codes) GetCodes(byDatabase bool) ([]*CodeModel, error) {
 			return nil, err
 		}
 
-		data := append(insert, update...)
+		// 清空后需要插入全部数据,不只是新增和修改的
+		data := list
 		// 2️⃣ 直接批量插入
 		batchSize := 3000 // 8000(2m16s) 5000(43s) 3000(11s) 1000(59s)
 		for i := 0; i < len(data); i += batchSize {
@@ -288,17 +471,30 @@ func (this *Codes) GetCodes(byDatabase bool) ([]*CodeModel, error) {
 				}
 			}
 			for _, v := range update {
-				if _, err := session.Where("Exchange=? and Code=? ", v.Exchange, v.Code).Cols("Name,LastPrice").Update(v); err != nil {
+				if _, err := session.Where("Exchange=? and Code=? ", v.Exchange, v.Code).Cols("Name,LastPrice,SecurityType").Update(v); err != nil {
 					return err
 				}
 			}
-			return nil
+			for _, v := range fill {
+				if _, err := session.ID(v.ID).Cols("SecurityType").Update(v); err != nil {
+					return err
+				}
+			}
+			return batch(len(remove), 500, func(i, j int) error {
+				_, err := session.In("ID", remove[i:j]).Delete(new(CodeModel))
+				return err
+			})
 		})
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	//5. 记录改名,上市和退市的历史
+	if err := this.updateHistory(seen, fetched, time.Now().Format("20060102")); err != nil {
+		return nil, err
+	}
+
 	return list, nil
 }
 
@@ -312,15 +508,16 @@ func (*UpdateModel) TableName() string {
 }
 
 type CodeModel struct {
-	ID        int64   `json:"id"`                      //主键
-	Name      string  `json:"name"`                    //名称,有时候名称会变,例STxxx
-	Code      string  `json:"code" xorm:"index"`       //代码
-	Exchange  string  `json:"exchange" xorm:"index"`   //交易所
-	Multiple  uint16  `json:"multiple"`                //倍数
-	Decimal   int8    `json:"decimal"`                 //小数位
-	LastPrice float64 `json:"lastPrice"`               //昨收价格
-	EditDate  int64   `json:"editDate" xorm:"updated"` //修改时间
-	InDate    int64   `json:"inDate" xorm:"created"`   //创建时间
+	ID           int64                 `json:"id"`                        //主键
+	Name         string                `json:"name"`                      //名称,有时候名称会变,例STxxx
+	Code         string                `json:"code" xorm:"index"`         //代码
+	Exchange     string                `json:"exchange" xorm:"index"`     //交易所
+	SecurityType protocol.SecurityType `json:"securityType" xorm:"index"` //证券类型,按号段判断,见protocol.SecurityTypeOf
+	Multiple     uint16                `json:"multiple"`                  //倍数
+	Decimal      int8                  `json:"decimal"`                   //小数位
+	LastPrice    float64               `json:"lastPrice"`                 //昨收价格
+	EditDate     int64                 `json:"editDate" xorm:"updated"`   //修改时间
+	InDate       int64                 `json:"inDate" xorm:"created"`     //创建时间
 }
 
 func (*CodeModel) TableName() string {
@@ -331,9 +528,15 @@ func (this *CodeModel) FullCode() string {
 	return this.Exchange + this.Code
 }
 
+// Symbol 转换成protocol.Symbol,包含资产类别
+func (this *CodeModel) Symbol() (protocol.Symbol, error) {
+	return protocol.ParseSymbol(this.FullCode())
+}
+
+// Price 原来用于修正基金等3位小数的k线价格,
+// Deprecated: 客户端解析时已经按小数位把价格转换成厘,直接返回p,后续版本删除
 func (this *CodeModel) Price(p protocol.Price) protocol.Price {
-	return protocol.Price(float64(p) * math.Pow10(int(2-this.Decimal)))
-	//return p * protocol.Price(math.Pow10(int(2-this.Decimal)))
+	return p
 }
 
 func NewSessionFunc(db *xorm.Engine, fn func(session *xorm.Session) error) error {
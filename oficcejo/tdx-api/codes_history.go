@@ -0,0 +1,168 @@
+package tdx
+
+import (
+	"time"
+
+	"github.com/injoyai/tdx/protocol"
+	"xorm.io/xorm"
+)
+
+// CodeHistoryModel 代码的历史记录,同一个代码每个名称一条,例如戴帽摘帽会新增一条,
+// 有效区间是[StartDate,EndDate),EndDate为空表示当前有效,日期格式20060102
+type CodeHistoryModel struct {
+	ID        int64  `json:"id"`                    //主键
+	Code      string `json:"code" xorm:"index"`     //代码
+	Exchange  string `json:"exchange" xorm:"index"` //交易所
+	Name      string `json:"name"`                  //名称
+	StartDate string `json:"startDate"`             //首次出现该名称的日期,开始记录之前的代码取入库日期
+	LastDate  string `json:"lastDate"`              //最后一次出现的日期
+	EndDate   string `json:"endDate"`               //失效日期,改名或者退市(服务器不再返回)的那天,为空表示当前有效
+}
+
+func (*CodeHistoryModel) TableName() string {
+	return "code_history"
+}
+
+func (this *CodeHistoryModel) FullCode() string {
+	return this.Exchange + this.Code
+}
+
+// Valid 在指定日期是否有效,date格式20060102
+func (this *CodeHistoryModel) Valid(date string) bool {
+	return this.StartDate <= date && (this.EndDate == "" || date < this.EndDate)
+}
+
+// History 获取代码的历史名称,按时间正序,可以查询什么时候变成了ST,什么时候退市
+func (this *Codes) History(code string) ([]*CodeHistoryModel, error) {
+	code = this.AddExchange(code)
+	ls := []*CodeHistoryModel(nil)
+	if len(code) != 8 {
+		return ls, nil
+	}
+	err := this.db.Where("Exchange=? and Code=?", code[:2], code[2:]).Asc("ID").Find(&ls)
+	return ls, err
+}
+
+// AsOf 获取指定日期在市的代码,名称为当天的名称,包括之后退市的代码(只有代码,名称和类型),用于回测时避免幸存者偏差,
+// 只能查询到开始记录历史之后的数据,开始之前的代码按入库日期算
+func (this *Codes) AsOf(date time.Time) ([]*CodeModel, error) {
+	d := date.Format("20060102")
+	ls := []*CodeHistoryModel(nil)
+	if err := this.db.Where("StartDate<=?", d).Asc("ID").Find(&ls); err != nil {
+		return nil, err
+	}
+	result := []*CodeModel(nil)
+	for _, v := range ls {
+		if !v.Valid(d) {
+			continue
+		}
+		m := &CodeModel{
+			Name:         v.Name,
+			Code:         v.Code,
+			Exchange:     v.Exchange,
+			SecurityType: protocol.SecurityTypeOf(v.FullCode()),
+		}
+		if c := this.Get(v.FullCode()); c != nil {
+			cp := *c
+			cp.Name = v.Name
+			m = &cp
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// updateHistory 根据服务器返回的代码更新历史,seen是本次返回的全部代码,
+// exchanges是本次获取成功的交易所,只有这些交易所下未返回的代码才记为退市,避免服务器数据异常时误判
+func (this *Codes) updateHistory(seen []*CodeModel, exchanges []string, date string) error {
+
+	ls := []*CodeHistoryModel(nil)
+	if err := this.db.Where("EndDate=?", "").Find(&ls); err != nil {
+		return err
+	}
+	current := make(map[string]*CodeHistoryModel, len(ls))
+	for _, v := range ls {
+		current[v.FullCode()] = v
+	}
+
+	insert := []*CodeHistoryModel(nil)
+	end := []int64(nil)  //改名或者退市的
+	last := []int64(nil) //名称没变的,更新最后出现的日期
+	exist := make(map[string]bool, len(seen))
+	for _, v := range seen {
+		exist[v.FullCode()] = true
+		h, ok := current[v.FullCode()]
+		switch {
+		case !ok:
+			start := date
+			if v.InDate > 0 {
+				if in := time.Unix(v.InDate, 0).Format("20060102"); in < start {
+					start = in
+				}
+			}
+			insert = append(insert, &CodeHistoryModel{
+				Code:      v.Code,
+				Exchange:  v.Exchange,
+				Name:      v.Name,
+				StartDate: start,
+				LastDate:  date,
+			})
+		case h.Name != v.Name:
+			end = append(end, h.ID)
+			insert = append(insert, &CodeHistoryModel{
+				Code:      v.Code,
+				Exchange:  v.Exchange,
+				Name:      v.Name,
+				StartDate: date,
+				LastDate:  date,
+			})
+		case h.LastDate != date:
+			last = append(last, h.ID)
+		}
+	}
+
+	fetched := make(map[string]bool, len(exchanges))
+	for _, v := range exchanges {
+		fetched[v] = true
+	}
+	for _, v := range ls {
+		if fetched[v.Exchange] && !exist[v.FullCode()] {
+			end = append(end, v.ID)
+		}
+	}
+
+	return NewSessionFunc(this.db, func(session *xorm.Session) error {
+		err := batch(len(insert), 500, func(i, j int) error {
+			_, err := session.Insert(insert[i:j])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		err = batch(len(end), 500, func(i, j int) error {
+			_, err := session.In("ID", end[i:j]).Cols("EndDate").Update(&CodeHistoryModel{EndDate: date})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return batch(len(last), 500, func(i, j int) error {
+			_, err := session.In("ID", last[i:j]).Cols("LastDate").Update(&CodeHistoryModel{LastDate: date})
+			return err
+		})
+	})
+}
+
+// batch 分批处理,sqlite的参数数量有限制
+func batch(n, size int, f func(i, j int) error) error {
+	for i := 0; i < n; i += size {
+		j := i + size
+		if j > n {
+			j = n
+		}
+		if err := f(i, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package tdx
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/injoyai/tdx/protocol"
+)
+
+// 搜索的匹配程度,越大越靠前
+const (
+	scoreCodeExact       = 100 //代码完全一致,例000001,sz000001,000001.SZ
+	scoreCodePrefix      = 90  //代码前缀,例00000
+	scoreNameExact       = 85  //名称完全一致
+	scoreNamePrefix      = 80  //名称前缀,例平安
+	scoreInitialsExact   = 75  //拼音首字母完全一致,例payh
+	scoreInitialsPrefix  = 70  //拼音首字母前缀,例pay
+	scorePinyinPrefix    = 65  //全拼前缀,例pingan
+	scoreNameContain     = 60  //名称包含,例银行
+	scoreCodeContain     = 55  //代码包含
+	scoreInitialsContain = 50  //拼音首字母包含,例yh
+	scorePinyinContain   = 45  //全拼包含,例yinhang
+	scoreFuzzyName       = 30  //名称按顺序包含每个字,例平银
+	scoreFuzzyInitials   = 25  //拼音首字母按顺序包含每个字母,例ph
+	scoreFuzzyPinyin     = 20  //全拼按顺序包含每个字母
+)
+
+// searchEntry 搜索索引,名称和拼音统一成小写
+type searchEntry struct {
+	model    *CodeModel
+	name     string
+	initials []string //拼音首字母,多音字有多个
+	pinyins  []string //全拼,多音字有多个
+}
+
+func newSearchIndex(list []*CodeModel) []*searchEntry {
+	index := make([]*searchEntry, 0, len(list))
+	for _, v := range list {
+		initials, pinyins := pinyin(v.Name, 8)
+		index = append(index, &searchEntry{
+			model:    v,
+			name:     strings.ToLower(v.Name),
+			initials: initials,
+			pinyins:  pinyins,
+		})
+	}
+	return index
+}
+
+// score 匹配程度,0表示不匹配,full是解析后带交易所的代码,解析不了为空
+func (this *searchEntry) score(keyword, full string) int {
+	code := this.model.Code
+	switch {
+	case code == keyword || this.model.FullCode() == full:
+		return scoreCodeExact
+	case strings.HasPrefix(code, keyword):
+		return scoreCodePrefix
+	case this.name == keyword:
+		return scoreNameExact
+	case strings.HasPrefix(this.name, keyword):
+		return scoreNamePrefix
+	case anyOf(this.initials, func(s string) bool { return s == keyword }):
+		return scoreInitialsExact
+	case anyOf(this.initials, func(s string) bool { return strings.HasPrefix(s, keyword) }):
+		return scoreInitialsPrefix
+	case anyOf(this.pinyins, func(s string) bool { return strings.HasPrefix(s, keyword) }):
+		return scorePinyinPrefix
+	case strings.Contains(this.name, keyword):
+		return scoreNameContain
+	case strings.Contains(code, keyword):
+		return scoreCodeContain
+	case anyOf(this.initials, func(s string) bool { return strings.Contains(s, keyword) }):
+		return scoreInitialsContain
+	case anyOf(this.pinyins, func(s string) bool { return strings.Contains(s, keyword) }):
+		return scorePinyinContain
+	}
+	//模糊匹配至少2个字,否则单个字母会匹配到大部分代码
+	if utf8.RuneCountInString(keyword) < 2 {
+		return 0
+	}
+	switch {
+	case subsequence(this.name, keyword):
+		return scoreFuzzyName
+	case anyOf(this.initials, func(s string) bool { return subsequence(s, keyword) }):
+		return scoreFuzzyInitials
+	case anyOf(this.pinyins, func(s string) bool { return subsequence(s, keyword) }):
+		return scoreFuzzyPinyin
+	}
+	return 0
+}
+
+// Search 搜索代码,支持代码(包括sz000001等格式),名称,拼音首字母(例payh),全拼和模糊匹配,
+// 按匹配程度排序,相同的按证券类型(股票,指数,基金...)和名称长度排序,limit<=0表示不限制,types为空表示全部类型
+func (this *Codes) Search(keyword string, limit int, types ...protocol.SecurityType) []*CodeModel {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if keyword == "" {
+		return nil
+	}
+	full := ""
+	if s, err := protocol.ParseSymbol(keyword); err == nil && len(keyword) != 6 {
+		full = s.String()
+	}
+	match := make(map[protocol.SecurityType]bool, len(types))
+	for _, v := range types {
+		match[v] = true
+	}
+
+	this.mu.RLock()
+	index := this.search
+	this.mu.RUnlock()
+
+	type result struct {
+		*searchEntry
+		score int
+	}
+	ls := []result(nil)
+	for _, v := range index {
+		if len(types) > 0 && !match[v.model.SecurityType] {
+			continue
+		}
+		if score := v.score(keyword, full); score > 0 {
+			ls = append(ls, result{searchEntry: v, score: score})
+		}
+	}
+
+	sort.Slice(ls, func(i, j int) bool {
+		a, b := ls[i], ls[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if x, y := typeOrder(a.model.SecurityType), typeOrder(b.model.SecurityType); x != y {
+			return x < y
+		}
+		if x, y := utf8.RuneCountInString(a.name), utf8.RuneCountInString(b.name); x != y {
+			return x < y
+		}
+		return a.model.FullCode() < b.model.FullCode()
+	})
+
+	if limit > 0 && len(ls) > limit {
+		ls = ls[:limit]
+	}
+	models := make([]*CodeModel, len(ls))
+	for i, v := range ls {
+		models[i] = v.model
+	}
+	return models
+}
+
+// typeOrder 相同匹配程度时证券类型的顺序,同protocol.SecurityTypes
+func typeOrder(t protocol.SecurityType) int {
+	for i, v := range protocol.SecurityTypes {
+		if v == t {
+			return i
+		}
+	}
+	return len(protocol.SecurityTypes)
+}
+
+func anyOf(ls []string, f func(s string) bool) bool {
+	for _, v := range ls {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// subsequence s是否按顺序包含sub的每个字符,例平安银行包含平银
+func subsequence(s, sub string) bool {
+	for _, r := range sub {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package tdx
+
+import (
+	"context"
+	"fmt"
+	"github.com/injoyai/tdx/protocol"
+)
+
+const (
+	SectorGeneral  = "general"  //一般板块,来自block.dat
+	SectorConcept  = "concept"  //概念,来自block_gn.dat
+	SectorStyle    = "style"    //风格,来自block_fg.dat
+	SectorIndex    = "index"    //指数成分股,来自block_zs.dat
+	SectorIndustry = "industry" //通达信行业,来自tdxhy.cfg,名称来自incon.dat
+)
+
+// SectorFiles 板块类型对应的板块文件
+var SectorFiles = map[string]string{
+	SectorGeneral:  protocol.BlockFileDefault,
+	SectorConcept:  protocol.BlockFileConcept,
+	SectorStyle:    protocol.BlockFileStyle,
+	SectorIndex:    protocol.BlockFileIndex,
+	SectorIndustry: protocol.BlockFileIndustry,
+}
+
+// Sector 板块
+type Sector struct {
+	Name  string   `json:"name"`  //板块名称,例白酒
+	Kind  string   `json:"kind"`  //板块类型,一般,概念,风格,指数,行业
+	Codes []string `json:"codes"` //成分股,带交易所前缀,例sz000001
+}
+
+func (this *Sector) String() string {
+	return fmt.Sprintf("%s[%s](%d只)", this.Name, this.Kind, len(this.Codes))
+}
+
+// blockFile 已下载的板块文件
+type blockFile struct {
+	hash string
+	data []byte
+}
+
+// UpdateSectors 更新板块缓存,先请求板块文件的信息,hash变化时才重新下载,
+// 部分文件失败时使用之前下载的数据,并返回错误
+func (this *Codes) UpdateSectors() error {
+	this.sectorMu.Lock()
+	defer this.sectorMu.Unlock()
+
+	this.mu.RLock()
+	exchanges := this.exchanges
+	this.mu.RUnlock()
+
+	if this.blockFiles == nil {
+		this.blockFiles = make(map[string]*blockFile)
+	}
+	var lastErr error
+	sectors := []*Sector(nil)
+	codeSectors := make(map[string][]*Sector)
+	nameSectors := make(map[string][]*Sector)
+	for _, kind := range []string{SectorGeneral, SectorConcept, SectorStyle, SectorIndex, SectorIndustry} {
+		blocks, err := this.getSectorBlocks(kind)
+		if err != nil {
+			lastErr = err
+		}
+		for _, b := range blocks {
+			s := &Sector{Name: b.Name, Kind: kind}
+			for _, code := range b.Codes {
+				full := fullCode(exchanges, code)
+				s.Codes = append(s.Codes, full)
+				codeSectors[full] = append(codeSectors[full], s)
+			}
+			sectors = append(sectors, s)
+			nameSectors[s.Name] = append(nameSectors[s.Name], s)
+		}
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.sectors = sectors
+	this.codeSectors = codeSectors
+	this.nameSectors = nameSectors
+	return lastErr
+}
+
+// getSectorBlocks 获取板块类型的板块,文件下载失败时使用之前下载的数据,调用方需要持有sectorMu
+func (this *Codes) getSectorBlocks(kind string) ([]*protocol.Block, error) {
+	filename := SectorFiles[kind]
+	file, err := this.updateBlockFile(filename)
+	if err != nil {
+		err = fmt.Errorf("更新板块文件%s失败: %w", filename, err)
+	}
+	if file == nil {
+		return nil, err
+	}
+	if kind != SectorIndustry {
+		blocks, er := protocol.DecodeBlockFile(file.data)
+		if er != nil {
+			return nil, fmt.Errorf("解析板块文件%s失败: %w", filename, er)
+		}
+		return blocks, err
+	}
+	//行业名称在另外的文件中,获取失败时使用行业代码作为名称
+	names, er := this.updateBlockFile(protocol.BlockFileIndustryName)
+	if er != nil {
+		err = fmt.Errorf("更新板块文件%s失败: %w", protocol.BlockFileIndustryName, er)
+	}
+	var nameData []byte
+	if names != nil {
+		nameData = names.data
+	}
+	blocks, er := protocol.DecodeIndustryFile(file.data, nameData)
+	if er != nil {
+		return nil, fmt.Errorf("解析板块文件%s失败: %w", filename, er)
+	}
+	return blocks, err
+}
+
+// updateBlockFile 下载有变化的板块文件,失败时返回之前下载的数据(可能为nil)和错误,调用方需要持有sectorMu
+func (this *Codes) updateBlockFile(filename string) (*blockFile, error) {
+	old := this.blockFiles[filename]
+	meta, err := this.Client.GetBlockMeta(filename)
+	if err != nil {
+		return old, err
+	}
+	if old != nil && meta.Hash != "" && old.hash == meta.Hash {
+		return old, nil
+	}
+	data, err := this.Client.getBlockFileAll(context.Background(), filename, meta)
+	if err != nil {
+		return old, err
+	}
+	file := &blockFile{hash: meta.Hash, data: data}
+	this.blockFiles[filename] = file
+	return file, nil
+}
+
+// fullCode 板块文件中的代码不带交易所,根据代码库补全,代码库查不到的按股票规则补全
+func fullCode(exchanges map[string][]string, code string) string {
+	ls := exchanges[code]
+	switch len(ls) {
+	case 0:
+		return protocol.AddPrefix(code)
+	case 1:
+		return ls[0] + code
+	}
+	full := protocol.AddPrefix(code)
+	for _, v := range ls {
+		if v+code == full {
+			return full
+		}
+	}
+	return ls[0] + code
+}
+
+// GetSectors 获取全部板块,kinds为空则返回全部类型
+func (this *Codes) GetSectors(kinds ...string) []*Sector {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	if len(kinds) == 0 {
+		return append([]*Sector(nil), this.sectors...)
+	}
+	ls := []*Sector(nil)
+	for _, v := range this.sectors {
+		for _, kind := range kinds {
+			if v.Kind == kind {
+				ls = append(ls, v)
+				break
+			}
+		}
+	}
+	return ls
+}
+
+// Sectors 获取代码所属的板块,例sz000001
+func (this *Codes) Sectors(code string) []*Sector {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.codeSectors[this.addExchange(code)]
+}
+
+// Members 获取板块的成分股,例白酒,同名板块(例如行业和概念都有)会合并去重
+func (this *Codes) Members(sector string) []string {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	ls := []string(nil)
+	exist := make(map[string]bool)
+	for _, s := range this.nameSectors[sector] {
+		for _, code := range s.Codes {
+			if !exist[code] {
+				exist[code] = true
+				ls = append(ls, code)
+			}
+		}
+	}
+	return ls
+}
@@ -0,0 +1,172 @@
+package tdx_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+)
+
+func TestCodes_History(t *testing.T) {
+	s, c := dial(t)
+
+	codes, err := tdx.NewCodesSqlite(c, filepath.Join(t.TempDir(), "codes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(codes.Stop)
+	if m := codes.Get("sz000001"); m == nil || m.Name != "平安银行" {
+		t.Fatalf("sz000001: %v", m)
+	}
+	if m := codes.Get("sh000001"); m == nil || m.Name != "上证指数" {
+		t.Fatalf("sh000001: %v", m)
+	}
+
+	//平安银行改名,浦发银行退市
+	s.Fixture.Codes[protocol.ExchangeSZ][0].Name = "ST平安"
+	sh := s.Fixture.Codes[protocol.ExchangeSH]
+	s.Fixture.Codes[protocol.ExchangeSH] = []*protocol.Code{sh[0], sh[2]}
+	if err := codes.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().Format("20060102")
+	ls, err := codes.History("sz000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls) != 2 || ls[0].Name != "平安银行" || ls[0].EndDate != today || ls[1].Name != "ST平安" || ls[1].EndDate != "" {
+		t.Errorf("sz000001的历史: %v", ls)
+	}
+	if ls, _ := codes.History("sh000001"); len(ls) != 1 || ls[0].Name != "上证指数" {
+		t.Errorf("sh000001的历史: %v", ls)
+	}
+
+	universe, err := codes.AsOf(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, v := range universe {
+		names[v.FullCode()] = v.Name
+	}
+	if len(names) != 3 || names["sz000001"] != "ST平安" || names["sh000001"] != "上证指数" {
+		t.Errorf("当天在市的代码: %v", names)
+	}
+	if _, ok := names["sh600000"]; ok {
+		t.Errorf("退市的代码不应该在市: %v", names)
+	}
+
+	if universe, _ := codes.AsOf(time.Now().AddDate(0, 0, -1)); len(universe) != 0 {
+		t.Errorf("开始记录之前不应该有数据: %d", len(universe))
+	}
+}
+
+func TestCodes_OnChange(t *testing.T) {
+	s, c := dial(t)
+
+	codes, err := tdx.NewCodesSqlite(c, filepath.Join(t.TempDir(), "codes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(codes.Stop)
+	if codes.Len() != 4 {
+		t.Fatalf("预期4个代码,得到%d", codes.Len())
+	}
+
+	var added, removed, renamed []*tdx.CodeModel
+	codes.OnChange(func(a, r, n []*tdx.CodeModel) {
+		added, removed, renamed = a, r, n
+	})
+
+	//新上市一只,浦发银行退市,平安银行改名
+	s.Fixture.Codes[protocol.ExchangeSZ] = []*protocol.Code{
+		{Code: "000001", Name: "ST平安", Multiple: 100, Decimal: 2},
+		{Code: "300750", Name: "宁德时代", Multiple: 100, Decimal: 2},
+	}
+	sh := s.Fixture.Codes[protocol.ExchangeSH]
+	s.Fixture.Codes[protocol.ExchangeSH] = []*protocol.Code{sh[0], sh[2]}
+
+	//并发读取不影响更新
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			codes.Get("000001")
+			codes.GetStocks()
+		}
+	}()
+	if err := codes.Update(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if len(added) != 1 || added[0].FullCode() != "sz300750" {
+		t.Errorf("added: %v", added)
+	}
+	if len(removed) != 1 || removed[0].FullCode() != "sh600000" {
+		t.Errorf("removed: %v", removed)
+	}
+	if len(renamed) != 1 || renamed[0].Name != "ST平安" {
+		t.Errorf("renamed: %v", renamed)
+	}
+	if codes.Get("sh600000") != nil || codes.Len() != 4 {
+		t.Errorf("退市的代码应该被删除: %d", codes.Len())
+	}
+}
+
+func TestCodes_Search(t *testing.T) {
+	s, c := dial(t)
+
+	//鑫是GB2312的二级汉字,不按拼音排序,由补充的表覆盖
+	s.Fixture.Codes[protocol.ExchangeSH] = append(s.Fixture.Codes[protocol.ExchangeSH],
+		&protocol.Code{Code: "600255", Name: "鑫科材料", Multiple: 100, Decimal: 2})
+
+	codes, err := tdx.NewCodesSqlite(c, filepath.Join(t.TempDir(), "codes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(codes.Stop)
+
+	for _, v := range []struct {
+		keyword string
+		types   []protocol.SecurityType
+		want    []string
+	}{
+		{"payh", nil, []string{"sz000001"}},
+		{"PingAn", nil, []string{"sz000001"}},
+		{"平安", nil, []string{"sz000001"}},
+		{"000001", nil, []string{"sz000001", "sh000001"}},
+		{"000001", []protocol.SecurityType{protocol.SecurityIndex}, []string{"sh000001"}},
+		{"sh000001", nil, []string{"sh000001"}},
+		{"000001.SZ", nil, []string{"sz000001"}},
+		{"hs300", nil, []string{"sh510300"}},
+		{"yh", nil, []string{"sh600000", "sz000001"}},
+		{"pyh", nil, []string{"sh600000", "sz000001"}},
+		{"szzs", nil, []string{"sh000001"}},
+		{"xkcl", nil, []string{"sh600255"}},
+		{"xinke", nil, []string{"sh600255"}},
+		{"xyz", nil, nil},
+	} {
+		got := []string(nil)
+		for _, m := range codes.Search(v.keyword, 0, v.types...) {
+			got = append(got, m.FullCode())
+		}
+		if len(got) < len(v.want) || len(v.want) == 0 && len(got) > 0 {
+			t.Errorf("%s: 预期%v,得到%v", v.keyword, v.want, got)
+			continue
+		}
+		for i := range v.want {
+			if got[i] != v.want[i] {
+				t.Errorf("%s: 预期%v,得到%v", v.keyword, v.want, got)
+				break
+			}
+		}
+	}
+
+	if ls := codes.Search("0", 2); len(ls) != 2 {
+		t.Errorf("limit: 预期2,得到%d", len(ls))
+	}
+}
@@ -0,0 +1,138 @@
+package tdx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/injoyai/base/maps/wait"
+	"github.com/injoyai/tdx/protocol"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrServerRejected 服务器拒绝了请求,一般是参数错误,例如代码不存在
+	ErrServerRejected = protocol.ErrServerRejected
+
+	// ErrDecode 响应数据解析失败,可通过errors.As获取*DecodeError,里面有原始数据帧
+	ErrDecode = protocol.ErrDecode
+
+	// ErrInvalidCode 代码格式错误,请求未发送
+	ErrInvalidCode = protocol.ErrInvalidCode
+
+	// ErrTimeout 等待服务器响应超时,服务器可能已经失效
+	ErrTimeout = errors.New("等待响应超时")
+
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("连接已关闭")
+)
+
+// DecodeError 响应数据解析失败,携带原始数据帧
+type DecodeError = protocol.DecodeError
+
+// waitResult 等待的响应结果
+type waitResult struct {
+	v   any
+	err error
+}
+
+// register 发送请求前注册等待,响应可能比开始等待更早到达(例如本地的服务器),先注册才不会丢失结果
+func register(w *wait.Entity, key string, timeout time.Duration) <-chan waitResult {
+	ch := make(chan waitResult, 1)
+	w.Async(key, func(v any, err error) {
+		select {
+		case ch <- waitResult{v: v, err: err}:
+		default:
+		}
+	}, 1, timeout)
+	return ch
+}
+
+// waitContext 等待register注册的响应,超时或上下文取消时注销等待,失败时执行clean清理缓存,
+// 服务器拒绝和解析失败的错误由handlerDealMessage通知,原样返回
+func waitContext(ctx context.Context, w *wait.Entity, key string, ch <-chan waitResult, timeout time.Duration, clean func()) (any, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case r := <-ch:
+		if r.err == nil {
+			return r.v, nil
+		}
+		err = r.err
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	//已经响应的key不存在,这里不会有影响
+	w.Done(key, nil, err)
+	clean()
+	return nil, err
+}
+
+// connState 连接状态和等待响应的请求,由OnConnected/OnDisconnect维护,
+// 不读取ios客户端的内部状态(和Run协程没有同步),断开时立即通知等待的请求ErrClosed,不用等到超时
+type connState struct {
+	connected int32
+	mu        sync.Mutex
+	pending   map[string]struct{}
+	wmu       sync.Mutex //ios的写入不是并发安全的,心跳和请求需要串行写入
+}
+
+// write 串行写入数据
+func (this *connState) write(w io.Writer, bs []byte) (int, error) {
+	this.wmu.Lock()
+	defer this.wmu.Unlock()
+	return w.Write(bs)
+}
+
+func (this *connState) closed() bool {
+	return atomic.LoadInt32(&this.connected) == 0
+}
+
+func (this *connState) connect() {
+	atomic.StoreInt32(&this.connected, 1)
+}
+
+// disconnect 标记断开,并通知所有等待响应的请求
+func (this *connState) disconnect(w *wait.Entity) {
+	atomic.StoreInt32(&this.connected, 0)
+	this.mu.Lock()
+	keys := make([]string, 0, len(this.pending))
+	for k := range this.pending {
+		keys = append(keys, k)
+	}
+	this.mu.Unlock()
+	for _, k := range keys {
+		w.Done(k, nil, ErrClosed)
+	}
+}
+
+// add 记录等待响应的请求,需要在判断closed之前调用,避免断开的通知被错过
+func (this *connState) add(key string) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.pending == nil {
+		this.pending = map[string]struct{}{}
+	}
+	this.pending[key] = struct{}{}
+}
+
+func (this *connState) del(key string) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	delete(this.pending, key)
+}
+
+// decodeCache 取出请求时缓存的参数并解析,响应在超时清理之后才到达等情况下缓存不存在,
+// 返回错误,由调用方包装成DecodeError通知等待的请求
+func decodeCache[C, R any](val any, bs []byte, decode func(bs []byte, c C) (R, error)) (any, error) {
+	c, ok := val.(C)
+	if !ok {
+		return nil, fmt.Errorf("缺少请求缓存,预期%T,得到%T", c, val)
+	}
+	return decode(bs, c)
+}
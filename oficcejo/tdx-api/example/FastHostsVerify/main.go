@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+)
+
+func main() {
+	ls := tdx.FastHostsVerify(tdx.Hosts...)
+	for _, v := range ls {
+		c := v.Capability
+		logs.Debugf("%s 握手:%s 请求:%s K线:%v 历史分笔:%d天 %s\n", v.Host, v.Spend, c.Latency, c.Kline, c.HistoryTradeDays, c.Info)
+	}
+	logs.Debug("可用数量:", len(ls), "总数量:", len(tdx.Hosts))
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/example/common"
+)
+
+func main() {
+	common.Test(func(c *tdx.Client) {
+		resp, err := c.GetAuction("sz000001")
+		logs.PanicErr(err)
+
+		for _, v := range resp.List {
+			logs.Debug(v)
+		}
+
+		logs.Debug("总数：", resp.Count)
+	})
+}
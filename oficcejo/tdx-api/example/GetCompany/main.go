@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/example/common"
+)
+
+func main() {
+	common.Test(func(c *tdx.Client) {
+		resp, err := c.GetCompanyCategories("sz000001")
+		logs.PanicErr(err)
+
+		for _, v := range resp.List {
+			logs.Debug(v)
+		}
+
+		if len(resp.List) > 0 {
+			content, err := c.GetCompanyContentByCategory("sz000001", resp.List[0])
+			logs.PanicErr(err)
+			logs.Debug(content.Content)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+)
+
+func main() {
+	c, err := tdx.DialExHostsRange(tdx.ExHosts, tdx.WithDebug())
+	logs.PanicErr(err)
+
+	markets, err := c.GetMarkets()
+	logs.PanicErr(err)
+	for _, v := range markets.List {
+		logs.Debug(v)
+	}
+
+	//股指期货
+	quote, err := c.GetQuote(protocol.ExMarketCFFEX, "IF2412")
+	logs.PanicErr(err)
+	logs.Debug(quote)
+
+	kline, err := c.GetKline(protocol.TypeKlineDay, protocol.ExMarketCFFEX, "IC2412", 0, 10)
+	logs.PanicErr(err)
+	for _, v := range kline.List {
+		logs.Debug(v)
+	}
+
+	//50ETF期权
+	options, err := c.GetInstrumentAll(protocol.ExMarketSHOption)
+	logs.PanicErr(err)
+	logs.Debug("期权数量：", options.Count)
+
+	<-c.Done()
+}
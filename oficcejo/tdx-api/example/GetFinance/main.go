@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/example/common"
+)
+
+func main() {
+	common.Test(func(c *tdx.Client) {
+		resp, err := c.GetFinance("sz000001")
+		logs.PanicErr(err)
+		logs.Debug(resp)
+
+		quotes, err := c.GetQuote("sz000001")
+		logs.PanicErr(err)
+		logs.Debug("换手率：", resp.TurnoverRate(quotes[0].TotalHand))
+		logs.Debug("总市值：", resp.TotalMarketValue(quotes[0].K.Close))
+	})
+}
@@ -10,10 +10,11 @@ func main() {
 	c, err := tdx.Dial("124.71.187.122:7709", tdx.WithDebug())
 	logs.PanicErr(err)
 
-	tdx.DefaultCodes, err = tdx.NewCodesSqlite(c, "./codes.db")
+	codes, err := tdx.NewCodesSqlite(c, "./codes.db")
 	logs.PanicErr(err)
 
-	_ = c
+	//非股票代码需要通过代码信息补全交易所前缀和价格小数位
+	c.SetCodeResolver(codes)
 
 	/*
 		发送：
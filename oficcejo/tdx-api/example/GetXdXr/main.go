@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/example/common"
+)
+
+func main() {
+	common.Test(func(c *tdx.Client) {
+		resp, err := c.GetXdXr("sz000001")
+		logs.PanicErr(err)
+
+		for _, v := range resp.List {
+			logs.Debug(v)
+		}
+
+		logs.Debug("总数：", resp.Count)
+
+		ks, err := c.GetKlineDayQfqAll("sz000001")
+		logs.PanicErr(err)
+
+		for _, v := range ks.List[len(ks.List)-10:] {
+			logs.Debug(v)
+		}
+	})
+}
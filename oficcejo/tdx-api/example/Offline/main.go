@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func main() {
+	//本地模拟的服务器,不需要网络
+	s, err := tdxtest.NewServer(nil)
+	logs.PanicErr(err)
+	defer s.Close()
+
+	c, err := tdx.Dial(s.Addr())
+	logs.PanicErr(err)
+	defer c.Close()
+
+	quotes, err := c.GetQuote("000001", "600000")
+	logs.PanicErr(err)
+	logs.Debug(quotes)
+
+	resp, err := c.GetKlineDayAll("000001")
+	logs.PanicErr(err)
+	for _, v := range resp.List {
+		logs.Debug(v)
+	}
+}
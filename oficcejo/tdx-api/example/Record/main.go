@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx"
+)
+
+func main() {
+	filename := "./capture.jsonl"
+
+	//抓包,记录请求和原始响应
+	f, err := os.Create(filename)
+	logs.PanicErr(err)
+	c, err := tdx.DialDefault(tdx.WithRecord(f))
+	logs.PanicErr(err)
+	_, err = c.GetQuote("000001")
+	logs.PanicErr(err)
+	c.Close()
+	f.Close()
+
+	//回放,不需要网络,可以反复调试解析
+	r, err := tdx.DialWith(tdx.NewReplayDial(filename))
+	logs.PanicErr(err)
+	defer r.Close()
+	quotes, err := r.GetQuote("000001")
+	logs.PanicErr(err)
+	logs.Debug(quotes)
+}
@@ -84,7 +84,7 @@ func (this *PullKlineMysql) Run(ctx context.Context, m *tdx.Manage) error {
 
 				//3. 从服务器获取数据
 				insert := Klines{}
-				err = m.Do(func(c *tdx.Client) error {
+				err = m.DoPriority(ctx, tdx.PriorityBulk, func(c *tdx.Client) error {
 					insert, err = this.pull(code, last.Date, table.Handler(c))
 					return err
 				})
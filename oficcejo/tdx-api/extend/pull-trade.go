@@ -66,8 +66,8 @@ func (this *PullTrade) PullYear(ctx context.Context, m *tdx.Manage, year int, co
 		date := t.Format("20060102")
 
 		var resp *protocol.TradeResp
-		err = m.Do(func(c *tdx.Client) error {
-			resp, err = c.GetHistoryTradeDay(date, code)
+		err = m.DoPriority(ctx, tdx.PriorityBulk, func(c *tdx.Client) error {
+			resp, err = c.GetHistoryMinuteTradeDayContext(ctx, date, code)
 			return err
 		})
 		if err != nil {
@@ -1,8 +1,13 @@
 package tdx
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/injoyai/base/types"
+	"github.com/injoyai/ios"
 	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx/protocol"
 	"net"
 	"strings"
 	"sync"
@@ -75,9 +80,19 @@ var (
 	WHHosts = []string{
 		"119.97.185.59", //电信
 	}
+
+	// ExHosts 扩展行情(期货,港股,期权)服务器地址,端口是7727
+	ExHosts = []string{
+		"112.74.214.43:7727",  //深圳双线
+		"120.24.0.77:7727",    //深圳双线
+		"47.107.75.159:7727",  //深圳双线
+		"106.14.95.149:7727",  //上海双线
+		"47.102.108.214:7727", //上海双线
+		"119.97.185.5:7727",   //武汉电信
+	}
 )
 
-// FastHosts 通过tcp(ping不可用)连接速度的方式筛选排序可用的地址
+// FastHosts 通过tcp(ping不可用)连接速度的方式筛选排序可用的地址,只要能建立连接就算可用,校验协议见FastHostsVerify
 func FastHosts(hosts ...string) []DialResult {
 	wg := sync.WaitGroup{}
 	wg.Add(len(hosts))
@@ -114,6 +129,149 @@ func FastHosts(hosts ...string) []DialResult {
 
 // DialResult 连接结果
 type DialResult struct {
-	Host  string
-	Spend time.Duration
+	Host       string
+	Spend      time.Duration   //FastHosts是tcp连接的耗时,FastHostsVerify是连接和握手的耗时
+	Capability *HostCapability //服务器支持的功能,FastHostsVerify时有值
+}
+
+// HostCapability 服务器支持的功能,通过FastHostsVerify探测
+type HostCapability struct {
+	Info             string        //握手时返回的服务器信息
+	Latency          time.Duration //探测请求(上海的证券数量)的耗时
+	Kline            bool          //是否支持K线
+	HistoryTradeDays int           //历史分笔能查询到多少天之前,按historyTradeDepths探测,0表示不支持
+}
+
+// historyTradeDepths 探测历史分笔深度的天数
+var historyTradeDepths = []int{7, 30, 90, 365, 1095}
+
+// FastHostsVerify 同FastHosts,并且校验协议,完成握手,请求上海的证券数量和上证指数的行情,
+// 探测K线和历史分笔的深度,握手失败或者返回数据不正确的地址会被剔除,按连接和握手的耗时排序
+func FastHostsVerify(hosts ...string) []DialResult {
+	wg := sync.WaitGroup{}
+	wg.Add(len(hosts))
+	mu := sync.Mutex{}
+	ls := types.List[DialResult](nil)
+	for _, host := range hosts {
+		go func(host string) {
+			defer wg.Done()
+			spend, capability, err := verifyHost(hostAddr(host), time.Second*3)
+			if err != nil {
+				logs.Errf("[%s] 校验失败: %v\n", host, err)
+				return
+			}
+			mu.Lock()
+			ls = append(ls, DialResult{
+				Host:       host,
+				Spend:      spend,
+				Capability: capability,
+			})
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+	return ls.Sort(func(a, b DialResult) bool {
+		return a.Spend < b.Spend
+	})
+}
+
+// verifyHost 校验服务地址,返回连接和握手的耗时
+func verifyHost(addr string, timeout time.Duration) (time.Duration, *HostCapability, error) {
+	var spend time.Duration
+	info := ""
+	c, err := DialWith(func(ctx context.Context) (ios.ReadWriteCloser, string, error) {
+		conn, resp, t, err := dialHandshake(ctx, addr, timeout)
+		if err != nil {
+			return nil, addr, err
+		}
+		spend, info = t, resp.Info
+		return conn, addr, nil
+	}, WithDebug(false))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer c.CloseAll()
+	c.SetTimeout(timeout)
+
+	capability := &HostCapability{Info: info}
+	start := time.Now()
+	count, err := c.GetCount(protocol.ExchangeSH)
+	if err != nil {
+		return 0, nil, err
+	}
+	capability.Latency = time.Since(start)
+	if count.Count == 0 {
+		return 0, nil, errors.New("证券数量为0")
+	}
+
+	quotes, err := c.GetQuote("sh000001")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(quotes) != 1 || quotes[0].Code != "000001" || quotes[0].K.Close <= 0 {
+		return 0, nil, errors.New("上证指数的行情不正确")
+	}
+
+	if resp, err := c.GetKlineDay("sh000001", 0, 1); err == nil && len(resp.List) > 0 {
+		capability.Kline = true
+	}
+
+	//每个深度往前找几个工作日,避免刚好是节假日
+	now := time.Now().In(protocol.LocationCST)
+	for _, days := range historyTradeDepths {
+		ok := false
+		date := now.AddDate(0, 0, -days)
+		for i := 0; i < 3 && !ok; i++ {
+			for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+				date = date.AddDate(0, 0, -1)
+			}
+			//只请求一页的一条,能查到就说明支持,不用拉取整天的数据
+			resp, err := c.GetHistoryMinuteTrade(date.Format("20060102"), "sz000001", 0, 1)
+			ok = err == nil && len(resp.List) > 0
+			date = date.AddDate(0, 0, -1)
+		}
+		if !ok {
+			break
+		}
+		capability.HistoryTradeDays = days
+	}
+
+	return spend, capability, nil
+}
+
+// dialHandshake 建立tcp连接并完成MConnect握手,返回握手响应和耗时
+func dialHandshake(ctx context.Context, addr string, timeout time.Duration) (net.Conn, *protocol.ConnectResp, time.Duration, error) {
+	start := time.Now()
+	c, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	resp, err := func() (*protocol.ConnectResp, error) {
+		if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		if _, err := c.Write(protocol.MConnect.Frame().Bytes()); err != nil {
+			return nil, err
+		}
+		bs, err := protocol.ReadFrom(c)
+		if err != nil {
+			return nil, err
+		}
+		f, err := protocol.Decode(bs)
+		if err != nil {
+			return nil, err
+		}
+		if f.Type != protocol.TypeConnect {
+			return nil, fmt.Errorf("握手响应的类型不正确: 0x%X", f.Type)
+		}
+		return protocol.MConnect.Decode(f.Data)
+	}()
+	if err == nil {
+		err = c.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		c.Close()
+		return nil, nil, 0, err
+	}
+	return c, resp, time.Since(start), nil
 }
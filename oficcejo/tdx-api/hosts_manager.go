@@ -0,0 +1,387 @@
+package tdx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/injoyai/ios"
+	"github.com/injoyai/ios/client"
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx/protocol"
+)
+
+// HostScore 服务地址的评分数据,Score越小越好
+type HostScore struct {
+	Host        string        `json:"host"`        //服务地址,带端口
+	Latency     time.Duration `json:"latency"`     //建立连接和握手的耗时,平滑后的值,0表示未检查过
+	ErrorRate   float64       `json:"errorRate"`   //请求的错误率,平滑后的值,越近的请求权重越大
+	TimeoutRate float64       `json:"timeoutRate"` //请求的超时率,平滑后的值
+	Lag         time.Duration `json:"lag"`         //最近一次检查时行情时间落后于最新服务器的时长,数据不更新的服务器会越来越大
+	Requests    int64         `json:"requests"`    //请求次数
+	Errors      int64         `json:"errors"`      //错误次数,不包括超时
+	Timeouts    int64         `json:"timeouts"`    //超时次数
+	Failures    int           `json:"failures"`    //连续检查或者连接失败的次数
+	Checked     time.Time     `json:"checked"`     //最近一次检查的时间
+	Blocked     time.Time     `json:"blocked"`     //连续超时后暂停使用到该时间
+
+	continued int //连续超时的次数
+}
+
+// Score 综合评分,越小越好,以毫秒为单位的耗时为基础,按错误率和超时率加权,行情落后和连续失败额外加分
+func (this *HostScore) Score() float64 {
+	latency := this.Latency
+	if latency <= 0 {
+		//未检查过的排在检查过的正常服务器之后
+		latency = time.Second
+	}
+	score := float64(latency) / float64(time.Millisecond)
+	score *= 1 + this.ErrorRate*5 + this.TimeoutRate*10
+	score += this.Lag.Seconds() * 100
+	score += float64(this.Failures) * 1000
+	return score
+}
+
+// HostConfig 服务地址管理的配置,零值使用默认值
+type HostConfig struct {
+	Filename     string        //评分保存的文件,例./data/database/hosts.json,为空表示不保存
+	MaxTimeouts  int           //客户端连续超时多少次后切换到下一个服务地址,默认3
+	BlockTime    time.Duration //连续超时后暂停使用该地址多久,默认1分钟
+	DialTimeout  time.Duration //建立连接的超时时间,默认3秒
+	CheckTimeout time.Duration //检查时等待响应的超时时间,默认3秒
+}
+
+func (this *HostConfig) init() *HostConfig {
+	cfg := HostConfig{}
+	if this != nil {
+		cfg = *this
+	}
+	if cfg.MaxTimeouts <= 0 {
+		cfg.MaxTimeouts = 3
+	}
+	if cfg.BlockTime <= 0 {
+		cfg.BlockTime = time.Minute
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = time.Second * 3
+	}
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = time.Second * 3
+	}
+	return &cfg
+}
+
+// NewHostManager 管理服务地址,hosts为空时使用Hosts,有保存的评分时先加载,
+// 配合Check定时检查和DialHostManager使用,客户端请求的结果也会计入评分
+func NewHostManager(hosts []string, cfg *HostConfig) (*HostManager, error) {
+	if len(hosts) == 0 {
+		hosts = Hosts
+	}
+	m := &HostManager{
+		cfg:   cfg.init(),
+		hosts: make(map[string]*HostScore, len(hosts)),
+	}
+	for _, v := range hosts {
+		v = hostAddr(v)
+		if _, ok := m.hosts[v]; !ok {
+			m.hosts[v] = &HostScore{Host: v}
+			m.order = append(m.order, v)
+		}
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type HostManager struct {
+	cfg   *HostConfig
+	mu    sync.RWMutex
+	hosts map[string]*HostScore
+	order []string //原始顺序,评分相同时按该顺序
+}
+
+// Hosts 按评分排序的服务地址,暂停使用的排在最后
+func (this *HostManager) Hosts() []string {
+	ls := this.Scores()
+	hosts := make([]string, len(ls))
+	for i, v := range ls {
+		hosts[i] = v.Host
+	}
+	return hosts
+}
+
+// Scores 按评分排序的评分数据(副本),暂停使用的排在最后
+func (this *HostManager) Scores() []*HostScore {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	now := time.Now()
+	ls := make([]*HostScore, 0, len(this.order))
+	for _, v := range this.order {
+		cp := *this.hosts[v]
+		ls = append(ls, &cp)
+	}
+	sort.SliceStable(ls, func(i, j int) bool {
+		a, b := ls[i].Blocked.After(now), ls[j].Blocked.After(now)
+		if a != b {
+			return b
+		}
+		return ls[i].Score() < ls[j].Score()
+	})
+	return ls
+}
+
+// Record 记录一次请求的结果,返回是否需要切换服务地址,连续超时达到MaxTimeouts或者地址已暂停使用时需要切换
+func (this *HostManager) Record(host string, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		//调用方取消的不计入
+		return false
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	h, ok := this.hosts[hostAddr(host)]
+	if !ok {
+		return false
+	}
+	timeout := errors.Is(err, ErrTimeout)
+	failed := err != nil && !timeout && !errors.Is(err, ErrInvalidCode)
+	h.Requests++
+	h.ErrorRate = smooth(h.ErrorRate, failed, 0.05)
+	h.TimeoutRate = smooth(h.TimeoutRate, timeout, 0.05)
+	switch {
+	case timeout:
+		h.Timeouts++
+		h.continued++
+	case failed:
+		h.Errors++
+	default:
+		h.continued = 0
+	}
+	if !timeout {
+		return false
+	}
+	if h.Blocked.After(time.Now()) {
+		return true
+	}
+	if h.continued >= this.cfg.MaxTimeouts {
+		h.continued = 0
+		h.Blocked = time.Now().Add(this.cfg.BlockTime)
+		logs.Errf("[%s] 连续超时%d次,暂停使用%s\n", h.Host, this.cfg.MaxTimeouts, this.cfg.BlockTime)
+		return true
+	}
+	return false
+}
+
+// smooth 指数平滑,hit为本次是否命中
+func smooth(rate float64, hit bool, alpha float64) float64 {
+	x := 0.0
+	if hit {
+		x = 1
+	}
+	return rate*(1-alpha) + x*alpha
+}
+
+// Check 检查所有的服务地址,记录建立连接和握手的耗时,以及上证指数的行情时间,
+// 行情时间落后于最新的服务器记为Lag,连接失败或者返回空数据的记为失败,设置了Filename的会保存
+func (this *HostManager) Check(ctx context.Context) error {
+	this.mu.RLock()
+	hosts := append([]string(nil), this.order...)
+	this.mu.RUnlock()
+
+	type result struct {
+		latency    time.Duration
+		serverTime time.Time
+		err        error
+	}
+	results := make([]result, len(hosts))
+	wg := sync.WaitGroup{}
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, this.cfg.DialTimeout+this.cfg.CheckTimeout)
+			defer cancel()
+			results[i].latency, results[i].serverTime, results[i].err = this.probe(ctx, host)
+		}(i, host)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	newest := time.Time{}
+	for _, v := range results {
+		if v.err == nil && v.serverTime.After(newest) {
+			newest = v.serverTime
+		}
+	}
+
+	this.mu.Lock()
+	now := time.Now()
+	for i, host := range hosts {
+		h := this.hosts[host]
+		r := results[i]
+		h.Checked = now
+		if r.err != nil {
+			logs.Errf("[%s] 检查失败: %v\n", host, r.err)
+			h.Failures++
+			continue
+		}
+		h.Failures = 0
+		if h.Latency <= 0 {
+			h.Latency = r.latency
+		} else {
+			h.Latency = (h.Latency*7 + r.latency*3) / 10
+		}
+		h.Lag = newest.Sub(r.serverTime)
+	}
+	this.mu.Unlock()
+
+	return this.Save()
+}
+
+// probe 建立连接并握手,再请求上海的证券数量和上证指数的行情,返回握手的耗时和行情时间
+func (this *HostManager) probe(ctx context.Context, host string) (time.Duration, time.Time, error) {
+	latency := time.Duration(0)
+	c, err := DialWith(func(ctx context.Context) (ios.ReadWriteCloser, string, error) {
+		c, _, spend, err := dialHandshake(ctx, host, this.cfg.DialTimeout)
+		latency = spend
+		return c, host, err
+	}, WithDebug(false))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer c.CloseAll()
+	c.SetTimeout(this.cfg.CheckTimeout)
+
+	count, err := c.GetCountContext(ctx, protocol.ExchangeSH)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if count.Count == 0 {
+		return 0, time.Time{}, errors.New("证券数量为0")
+	}
+	quotes, err := c.GetQuoteContext(ctx, "sh000001")
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(quotes) == 0 || quotes[0].ServerTime.IsZero() {
+		return 0, time.Time{}, errors.New("行情数据为空")
+	}
+	return latency, quotes[0].ServerTime, nil
+}
+
+// Run 定时检查,直到上下文取消
+func (this *HostManager) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := this.Check(ctx); err != nil && ctx.Err() == nil {
+			logs.Err(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Dial 按评分从高到低尝试连接,跳过暂停使用的地址,全部暂停时按评分依次尝试,连接失败计入评分
+func (this *HostManager) Dial() ios.DialFunc {
+	return func(ctx context.Context) (ios.ReadWriteCloser, string, error) {
+		var err error
+		for _, host := range this.Hosts() {
+			select {
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			default:
+			}
+			var c net.Conn
+			c, err = (&net.Dialer{Timeout: this.cfg.DialTimeout}).DialContext(ctx, "tcp", host)
+			this.mu.Lock()
+			if err == nil {
+				this.hosts[host].Failures = 0
+			} else {
+				this.hosts[host].Failures++
+			}
+			this.mu.Unlock()
+			if err == nil {
+				return c, host, nil
+			}
+			logs.Err(err)
+		}
+		if err == nil {
+			err = errors.New("没有可用的服务地址")
+		}
+		return nil, "", err
+	}
+}
+
+// Save 保存评分,未设置Filename时不保存
+func (this *HostManager) Save() error {
+	if this.cfg.Filename == "" {
+		return nil
+	}
+	this.mu.RLock()
+	ls := make([]*HostScore, 0, len(this.order))
+	for _, v := range this.order {
+		ls = append(ls, this.hosts[v])
+	}
+	bs, err := json.MarshalIndent(ls, "", "  ")
+	this.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(this.cfg.Filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(this.cfg.Filename, bs, 0644)
+}
+
+// load 加载保存的评分,只保留当前管理的服务地址
+func (this *HostManager) load() error {
+	if this.cfg.Filename == "" {
+		return nil
+	}
+	bs, err := os.ReadFile(this.cfg.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ls := []*HostScore(nil)
+	if err := json.Unmarshal(bs, &ls); err != nil {
+		return fmt.Errorf("解析%s失败: %w", this.cfg.Filename, err)
+	}
+	for _, v := range ls {
+		if _, ok := this.hosts[v.Host]; ok {
+			this.hosts[v.Host] = v
+		}
+	}
+	return nil
+}
+
+// hostAddr 补全默认端口7709
+func hostAddr(host string) string {
+	if !strings.Contains(host, ":") {
+		host += ":7709"
+	}
+	return host
+}
+
+// DialHostManager 通过服务地址管理建立连接,默认断线重连,请求的结果计入评分,
+// 连续超时达到MaxTimeouts后断开,重连到评分最好的下一个地址
+func DialHostManager(m *HostManager, op ...client.Option) (*Client, error) {
+	//在连接之前设置,连接后的请求就会计入评分
+	op = append([]client.Option{WithRedial(), withClient(func(c *Client) { c.hosts = m })}, op...)
+	return DialWith(m.Dial(), op...)
+}
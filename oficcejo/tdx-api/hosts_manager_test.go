@@ -0,0 +1,90 @@
+package tdx_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func TestHostManager(t *testing.T) {
+	s1, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+	//行情时间落后10分钟的服务器
+	f := tdxtest.DefaultFixture()
+	f.Quotes["sh000001"].ServerTime = f.Quotes["sh000001"].ServerTime.Add(-time.Minute * 10)
+	s2, err := tdxtest.NewServer(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	cfg := &tdx.HostConfig{
+		Filename:    filepath.Join(t.TempDir(), "hosts.json"),
+		MaxTimeouts: 2,
+	}
+	m, err := tdx.NewHostManager([]string{s2.Addr(), s1.Addr()}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	scores := m.Scores()
+	if scores[0].Host != s1.Addr() || scores[1].Lag != time.Minute*10 {
+		t.Fatalf("预期行情落后的排在后面,得到%+v,%+v", scores[0], scores[1])
+	}
+
+	//评分保存到文件,重新加载
+	m2, err := tdx.NewHostManager([]string{s2.Addr(), s1.Addr()}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := m2.Hosts(); hosts[0] != s1.Addr() || m2.Scores()[1].Lag != time.Minute*10 {
+		t.Fatalf("预期加载保存的评分,得到%v", hosts)
+	}
+
+	//连续超时后切换到下一个服务地址
+	c, err := tdx.DialHostManager(m, tdx.WithDebug(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.CloseAll()
+	if c.GetKey() != s1.Addr() {
+		t.Fatalf("预期连接%s,得到%s", s1.Addr(), c.GetKey())
+	}
+	block := make(chan struct{})
+	defer close(block)
+	s1.Handle(protocol.TypeCount, func(f *protocol.Frame) ([]byte, error) {
+		<-block
+		return nil, errors.New("关闭")
+	})
+	c.SetTimeout(time.Millisecond * 100)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetCount(protocol.ExchangeSH); !errors.Is(err, tdx.ErrTimeout) {
+			t.Fatalf("预期超时,得到%v", err)
+		}
+	}
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) {
+		if _, err := c.GetCount(protocol.ExchangeSH); err == nil {
+			if c.GetKey() != s2.Addr() {
+				t.Fatalf("预期切换到%s,得到%s", s2.Addr(), c.GetKey())
+			}
+			if hosts := m.Hosts(); hosts[len(hosts)-1] != s1.Addr() {
+				t.Fatalf("预期暂停使用的排在最后,得到%v", hosts)
+			}
+			return
+		}
+		<-time.After(time.Millisecond * 50)
+	}
+	t.Fatal("预期切换服务地址")
+}
@@ -0,0 +1,53 @@
+package tdx_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func TestFastHostsVerify(t *testing.T) {
+	//最近40天都有历史分笔
+	f := tdxtest.DefaultFixture()
+	trades := f.HistoryTrades["20241115"]["sz000001"]
+	for i := 0; i <= 40; i++ {
+		f.SetHistoryTrade(time.Now().In(protocol.LocationCST).AddDate(0, 0, -i).Format("20060102"), "sz000001", trades)
+	}
+	s1, err := tdxtest.NewServer(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+
+	//行情请求异常的服务器
+	s2, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	s2.Handle(protocol.TypeQuote, func(f *protocol.Frame) ([]byte, error) {
+		return nil, errors.New("不支持")
+	})
+
+	//不能连接的地址
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := l.Addr().String()
+	l.Close()
+
+	ls := tdx.FastHostsVerify(s1.Addr(), s2.Addr(), dead)
+	if len(ls) != 1 || ls[0].Host != s1.Addr() {
+		t.Fatalf("预期只剩%s,得到%+v", s1.Addr(), ls)
+	}
+	c := ls[0].Capability
+	if c.Info != f.Info || !c.Kline || c.HistoryTradeDays != 30 || c.Latency <= 0 || ls[0].Spend <= 0 {
+		t.Fatalf("capability: %+v", c)
+	}
+}
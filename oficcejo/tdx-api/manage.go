@@ -31,13 +31,14 @@ func NewManageMysql(cfg *ManageConfig, op ...client.Option) (*Manage, error) {
 	if err != nil {
 		return nil, err
 	}
-	commonClient.Wait.SetTimeout(time.Second * 5)
+	commonClient.SetTimeout(time.Second * 5)
 
 	//代码管理
 	codes, err := NewCodesMysql(commonClient, cfg.CodesFilename)
 	if err != nil {
 		return nil, err
 	}
+	commonClient.SetCodeResolver(codes)
 
 	//工作日管理
 	workday, err := NewWorkdayMysql(commonClient, cfg.WorkdayFileName)
@@ -46,9 +47,14 @@ func NewManageMysql(cfg *ManageConfig, op ...client.Option) (*Manage, error) {
 	}
 
 	//连接池
-	p, err := NewPool(func() (*Client, error) {
-		return cfg.Dial(op...)
-	}, cfg.Number)
+	p, err := NewPoolWith(func() (*Client, error) {
+		c, err := cfg.Dial(op...)
+		if err != nil {
+			return nil, err
+		}
+		c.SetCodeResolver(codes)
+		return c, nil
+	}, cfg.poolConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -82,13 +88,14 @@ func NewManage(cfg *ManageConfig, op ...client.Option) (*Manage, error) {
 	if err != nil {
 		return nil, err
 	}
-	commonClient.Wait.SetTimeout(time.Second * 5)
+	commonClient.SetTimeout(time.Second * 5)
 
 	//代码管理
 	codes, err := NewCodesSqlite(commonClient, cfg.CodesFilename)
 	if err != nil {
 		return nil, err
 	}
+	commonClient.SetCodeResolver(codes)
 
 	//工作日管理
 	workday, err := NewWorkdaySqlite(commonClient, cfg.WorkdayFileName)
@@ -97,9 +104,14 @@ func NewManage(cfg *ManageConfig, op ...client.Option) (*Manage, error) {
 	}
 
 	//连接池
-	p, err := NewPool(func() (*Client, error) {
-		return cfg.Dial(op...)
-	}, cfg.Number)
+	p, err := NewPoolWith(func() (*Client, error) {
+		c, err := cfg.Dial(op...)
+		if err != nil {
+			return nil, err
+		}
+		c.SetCodeResolver(codes)
+		return c, nil
+	}, cfg.poolConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -145,8 +157,16 @@ func (this *Manage) AddWorkdayTask(spec string, f func(m *Manage)) {
 }
 
 type ManageConfig struct {
-	Number          int                                                //客户端数量
+	Number          int                                                //客户端数量,Pool为nil时使用
+	Pool            *PoolConfig                                        //连接池配置,例如设置Max在繁忙时扩容
 	CodesFilename   string                                             //代码数据库位置
 	WorkdayFileName string                                             //工作日数据库位置
 	Dial            func(op ...client.Option) (cli *Client, err error) //默认连接方式
 }
+
+func (this *ManageConfig) poolConfig() *PoolConfig {
+	if this.Pool != nil {
+		return this.Pool
+	}
+	return &PoolConfig{Min: this.Number, Max: this.Number}
+}
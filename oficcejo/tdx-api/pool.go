@@ -1,68 +1,393 @@
 package tdx
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/injoyai/base/safe"
+	"github.com/injoyai/logs"
 )
 
-// NewPool 简易版本的连接池
-func NewPool(dial func() (*Client, error), number int) (*Pool, error) {
-	if number <= 0 {
-		number = 1
+// Priority 请求的类别,连接池繁忙时按类别调度
+type Priority int
+
+const (
+	PriorityRealtime    Priority = iota //实时行情,例如盘口报价,有等待时最先分配
+	PriorityInteractive                 //交互请求,例如页面上的查询,Get和Do默认的类别
+	PriorityBulk                        //批量任务,例如拉取历史K线和分时成交,不能使用保留的客户端
+	priorityNum
+)
+
+func (this Priority) String() string {
+	switch this {
+	case PriorityRealtime:
+		return "realtime"
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBulk:
+		return "bulk"
+	}
+	return fmt.Sprintf("Priority(%d)", int(this))
+}
+
+// PoolConfig 连接池配置,零值使用默认值
+type PoolConfig struct {
+	Min           int           //最少的客户端数量,断开或者失效后在后台补上,默认1
+	Max           int           //最多的客户端数量,繁忙时按需增加,默认等于Min
+	Reserved      int           //给实时和交互请求保留的客户端数量,批量任务最多同时使用Max-Reserved个,默认Max>1时为1,小于0表示不保留
+	BulkShare     int           //交互请求和批量任务都在等待时,每分配BulkShare次给交互请求后分配1次给批量任务,默认4
+	CheckInterval time.Duration //检查空闲客户端的间隔,默认30秒
+	PingTimeout   time.Duration //检查时等待响应的超时时间,默认3秒
+	IdleTimeout   time.Duration //超过Min的客户端空闲多久后关闭,默认5分钟
+	MaxErrors     int           //连续超时或者断开多少次后替换客户端,默认3
+}
+
+func (this *PoolConfig) init() *PoolConfig {
+	cfg := PoolConfig{}
+	if this != nil {
+		cfg = *this
+	}
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Second * 30
+	}
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = time.Second * 3
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = time.Minute * 5
+	}
+	if cfg.MaxErrors <= 0 {
+		cfg.MaxErrors = 3
 	}
-	ch := make(chan *Client, number)
+	switch {
+	case cfg.Reserved < 0:
+		cfg.Reserved = 0
+	case cfg.Reserved == 0 && cfg.Max > 1:
+		cfg.Reserved = 1
+	}
+	if cfg.Reserved >= cfg.Max {
+		//批量任务至少能使用1个
+		cfg.Reserved = cfg.Max - 1
+	}
+	if cfg.BulkShare <= 0 {
+		cfg.BulkShare = 4
+	}
+	return &cfg
+}
+
+// PoolStat 连接池中客户端的统计信息
+type PoolStat struct {
+	Addr      string        `json:"addr"`      //服务器地址
+	Idle      bool          `json:"idle"`      //是否空闲
+	Priority  string        `json:"priority"`  //使用中的请求类别,空闲时为空
+	Latency   time.Duration `json:"latency"`   //最近一次检查的耗时
+	Requests  int64         `json:"requests"`  //执行的次数
+	Errors    int64         `json:"errors"`    //超时或者断开的次数
+	Continued int64         `json:"continued"` //连续的错误次数,达到MaxErrors后会被替换
+	Created   time.Time     `json:"created"`   //建立连接的时间
+	LastUsed  time.Time     `json:"lastUsed"`  //最后使用的时间
+}
+
+// pooled 连接池中的客户端
+type pooled struct {
+	*Client
+	created   time.Time
+	lastUsed  time.Time
+	latency   int64 //纳秒
+	requests  int64
+	errors    int64
+	continued int64
+	priority  Priority //使用中的请求类别
+}
+
+// waiter 等待客户端的请求,分配后通过ch发送
+type waiter struct {
+	priority Priority
+	ch       chan *pooled
+}
+
+// NewPool 连接池,固定number个客户端,见NewPoolWith
+func NewPool(dial func() (*Client, error), number int) (*Pool, error) {
+	return NewPoolWith(dial, &PoolConfig{Min: number, Max: number})
+}
+
+// NewPoolWith 连接池,定时检查空闲的客户端,失效的在后台替换,繁忙时在Min和Max之间扩容,
+// 一个客户端都连接不上时返回错误,部分失败的会在后台重试
+func NewPoolWith(dial func() (*Client, error), cfg *PoolConfig) (*Pool, error) {
 	p := &Pool{
-		ch: ch,
-		Closer: safe.NewCloser().SetCloseFunc(func(err error) error {
-			close(ch)
-			return nil
-		}),
+		cfg:  cfg.init(),
+		dial: dial,
+		all:  make(map[*Client]*pooled),
 	}
-	for i := 0; i < number; i++ {
+	p.Closer = safe.NewCloser().SetCloseFunc(func(err error) error {
+		p.mu.Lock()
+		ls := make([]*pooled, 0, len(p.all))
+		for _, v := range p.all {
+			ls = append(ls, v)
+		}
+		p.all = map[*Client]*pooled{}
+		p.idle = nil
+		p.mu.Unlock()
+		for _, v := range ls {
+			v.CloseAll()
+		}
+		return nil
+	})
+
+	//先同步建立连接,失败的在后台重试
+	var lastErr error
+	for i := 0; i < p.cfg.Min; i++ {
 		c, err := dial()
 		if err != nil {
-			return nil, err
+			logs.Err(err)
+			lastErr = err
+			continue
 		}
-		p.ch <- c
+		p.mu.Lock()
+		p.add(c, true)
+		p.mu.Unlock()
 	}
+	if lastErr != nil && len(p.all) == 0 {
+		//一个都连接不上,大概率是网络或者地址的问题,后台重试也不会成功,Get会一直等待
+		p.Close()
+		return nil, lastErr
+	}
+	p.fill()
+
+	go p.run()
 	return p, nil
 }
 
 type Pool struct {
-	ch chan *Client
+	cfg     *PoolConfig
+	dial    func() (*Client, error)
+	mu      sync.Mutex
+	all     map[*Client]*pooled    //全部的客户端,包括使用中的
+	idle    []*pooled              //空闲的客户端,后进先出
+	dialing int                    //正在建立的连接数量
+	waiters [priorityNum][]*waiter //每个类别等待中的请求,先进先出
+	using   [priorityNum]int       //每个类别使用中的客户端数量
+	served  int                    //连续分配给交互请求的次数,用于按比例分配给批量任务
 	*safe.Closer
 }
 
+// Get 获取客户端,没有可用的客户端时一直等待,使用后需要Put
 func (this *Pool) Get() (*Client, error) {
-	select {
-	case <-this.Done():
-		return nil, this.Err()
-	case c, ok := <-this.ch:
-		if !ok {
-			return nil, errors.New("已关闭")
+	return this.GetContext(context.Background())
+}
+
+// GetContext 获取客户端,没有可用的客户端时等待,直到上下文取消,繁忙时会在Max内扩容,按交互请求调度
+func (this *Pool) GetContext(ctx context.Context) (*Client, error) {
+	return this.GetPriority(ctx, PriorityInteractive)
+}
+
+// GetPriority 按请求类别获取客户端,繁忙时实时行情最先分配,交互请求和批量任务按BulkShare的比例分配,
+// 批量任务不能使用保留的客户端,所以不会占满连接池
+func (this *Pool) GetPriority(ctx context.Context, priority Priority) (*Client, error) {
+	if priority < 0 || priority >= priorityNum {
+		return nil, fmt.Errorf("无效的请求类别: %v", priority)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		this.mu.Lock()
+		if this.Closed() {
+			this.mu.Unlock()
+			return nil, this.Err()
+		}
+		if this.allow(priority) {
+			if n := len(this.idle); n > 0 {
+				p := this.idle[n-1]
+				this.idle = this.idle[:n-1]
+				if p.Client.Closed() {
+					//已经断开的丢弃,在后台补上
+					this.mu.Unlock()
+					this.remove(p.Client)
+					continue
+				}
+				this.take(p, priority)
+				this.mu.Unlock()
+				return p.Client, nil
+			}
+			if len(this.all)+this.dialing < this.cfg.Max {
+				//扩容,建立连接期间先计入使用中,避免同时扩容的批量任务超过限制
+				this.dialing++
+				this.using[priority]++
+				this.mu.Unlock()
+				c, err := this.dial()
+				this.mu.Lock()
+				this.dialing--
+				this.using[priority]--
+				if err == nil && this.Closed() {
+					this.mu.Unlock()
+					c.CloseAll()
+					return nil, this.Err()
+				} else if err == nil {
+					p := this.add(c, false)
+					this.take(p, priority)
+					this.mu.Unlock()
+					return c, nil
+				}
+				logs.Err(err)
+			}
+		}
+		//排队等待,直到分配到客户端,期间一直保持在队列中的位置
+		w := &waiter{priority: priority, ch: make(chan *pooled, 1)}
+		this.waiters[priority] = append(this.waiters[priority], w)
+		this.mu.Unlock()
+		return this.wait(ctx, w)
+	}
+}
+
+// wait 等待分配客户端,扩容失败时定时重试,扩容的客户端按队列顺序分配,不会打乱等待的先后
+func (this *Pool) wait(ctx context.Context, w *waiter) (*Client, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case p := <-w.ch:
+			if !p.Client.Closed() {
+				return p.Client, nil
+			}
+			//已经断开的丢弃,放回队列最前面继续等待
+			this.mu.Lock()
+			this.using[p.priority]--
+			this.waiters[w.priority] = append([]*waiter{w}, this.waiters[w.priority]...)
+			this.schedule()
+			this.mu.Unlock()
+			this.remove(p.Client)
+		case <-ctx.Done():
+			if !this.cancel(w) {
+				//已经分配到客户端,在锁外取出后归还
+				this.giveBack(<-w.ch)
+			}
+			return nil, ctx.Err()
+		case <-this.Done():
+			return nil, this.Err()
+		case <-ticker.C:
+			this.expand()
 		}
-		return c, nil
 	}
 }
 
+// expand 扩容一个客户端,加入空闲后分配给等待的请求,达到Max时不扩容
+func (this *Pool) expand() {
+	this.mu.Lock()
+	if this.Closed() || len(this.all)+this.dialing >= this.cfg.Max {
+		this.mu.Unlock()
+		return
+	}
+	this.dialing++
+	this.mu.Unlock()
+	c, err := this.dial()
+	this.mu.Lock()
+	this.dialing--
+	if err == nil && !this.Closed() {
+		this.add(c, true)
+		this.mu.Unlock()
+		return
+	}
+	this.mu.Unlock()
+	if err != nil {
+		logs.Err(err)
+		return
+	}
+	c.CloseAll()
+}
+
+// cancel 从等待队列中移除,返回false说明已经分配了客户端,需要从w.ch取出归还
+func (this *Pool) cancel(w *waiter) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	ls := this.waiters[w.priority]
+	for i, v := range ls {
+		if v == w {
+			this.waiters[w.priority] = append(ls[:i], ls[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// giveBack 归还分配后没有使用的客户端,不计入统计
+func (this *Pool) giveBack(p *pooled) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.using[p.priority]--
+	if this.all[p.Client] == p {
+		this.idle = append(this.idle, p)
+		this.schedule()
+	}
+}
+
+// Put 归还客户端,已经断开的会被丢弃并在后台补上
 func (this *Pool) Put(c *Client) {
-	select {
-	case <-this.Done():
-		c.Close()
+	this.put(c, nil)
+}
+
+// put 归还客户端,并记录这次使用的结果,连续超时或者断开达到MaxErrors的会被替换
+func (this *Pool) put(c *Client, err error) {
+	this.mu.Lock()
+	p, ok := this.all[c]
+	if !ok {
+		//已关闭或者不是连接池中的客户端
+		this.mu.Unlock()
+		c.CloseAll()
+		return
+	}
+	this.using[p.priority]--
+	p.requests++
+	p.lastUsed = time.Now()
+	switch {
+	case errors.Is(err, ErrTimeout) || errors.Is(err, ErrClosed):
+		p.errors++
+		p.continued++
+	case err == nil:
+		p.continued = 0
+	}
+	if c.Closed() || p.continued >= int64(this.cfg.MaxErrors) {
+		this.mu.Unlock()
+		this.remove(c)
 		return
-	case this.ch <- c:
 	}
+	this.idle = append(this.idle, p)
+	this.schedule()
+	this.mu.Unlock()
 }
 
+// Do 获取客户端并执行,执行结果用于判断客户端是否失效,按交互请求调度
 func (this *Pool) Do(fn func(c *Client) error) error {
-	c, err := this.Get()
+	return this.DoContext(context.Background(), fn)
+}
+
+// DoContext 同Do,获取客户端时等待到上下文取消为止
+func (this *Pool) DoContext(ctx context.Context, fn func(c *Client) error) error {
+	return this.DoPriority(ctx, PriorityInteractive, fn)
+}
+
+// DoPriority 同DoContext,按请求类别调度,例如拉取历史数据使用PriorityBulk,避免影响行情查询
+func (this *Pool) DoPriority(ctx context.Context, priority Priority, fn func(c *Client) error) error {
+	c, err := this.GetPriority(ctx, priority)
 	if err != nil {
 		return err
 	}
-	defer this.Put(c)
-	return fn(c)
+	err = fn(c)
+	this.put(c, err)
+	return err
 }
 
+// Go 获取客户端并在协程中执行
 func (this *Pool) Go(fn func(c *Client)) error {
 	c, err := this.Get()
 	if err != nil {
@@ -74,3 +399,236 @@ func (this *Pool) Go(fn func(c *Client)) error {
 	}(c)
 	return nil
 }
+
+// Len 客户端数量,包括使用中的
+func (this *Pool) Len() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return len(this.all)
+}
+
+// Stats 每个客户端的统计信息
+func (this *Pool) Stats() []*PoolStat {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	idle := make(map[*pooled]bool, len(this.idle))
+	for _, v := range this.idle {
+		idle[v] = true
+	}
+	ls := make([]*PoolStat, 0, len(this.all))
+	for _, v := range this.all {
+		priority := ""
+		if !idle[v] {
+			priority = v.priority.String()
+		}
+		ls = append(ls, &PoolStat{
+			Addr:      v.GetKey(),
+			Idle:      idle[v],
+			Priority:  priority,
+			Latency:   time.Duration(atomic.LoadInt64(&v.latency)),
+			Requests:  v.requests,
+			Errors:    v.errors,
+			Continued: v.continued,
+			Created:   v.created,
+			LastUsed:  v.lastUsed,
+		})
+	}
+	return ls
+}
+
+// add 添加客户端,调用方需要持有锁
+func (this *Pool) add(c *Client, idle bool) *pooled {
+	p := &pooled{Client: c, created: time.Now(), lastUsed: time.Now()}
+	this.all[c] = p
+	if idle {
+		this.idle = append(this.idle, p)
+		this.schedule()
+	}
+	return p
+}
+
+// take 标记客户端被priority类别使用,调用方需要持有锁
+func (this *Pool) take(p *pooled, priority Priority) {
+	p.priority = priority
+	this.using[priority]++
+}
+
+// allow 是否还能分配给该类别,批量任务不能使用保留的客户端,调用方需要持有锁
+func (this *Pool) allow(priority Priority) bool {
+	return priority != PriorityBulk || this.using[PriorityBulk] < this.cfg.Max-this.cfg.Reserved
+}
+
+// waiting 等待中的请求数量,调用方需要持有锁
+func (this *Pool) waiting() int {
+	n := 0
+	for _, v := range this.waiters {
+		n += len(v)
+	}
+	return n
+}
+
+// next 取出下一个分配的请求,实时行情优先,交互请求和批量任务都在等待时按BulkShare的比例分配,
+// 没有可以分配的返回nil,调用方需要持有锁
+func (this *Pool) next() *waiter {
+	interactive := len(this.waiters[PriorityInteractive]) > 0
+	bulk := len(this.waiters[PriorityBulk]) > 0 && this.allow(PriorityBulk)
+	var priority Priority
+	switch {
+	case len(this.waiters[PriorityRealtime]) > 0:
+		priority = PriorityRealtime
+	case interactive && bulk && this.served >= this.cfg.BulkShare:
+		this.served = 0
+		priority = PriorityBulk
+	case interactive:
+		if bulk {
+			this.served++
+		}
+		priority = PriorityInteractive
+	case bulk:
+		priority = PriorityBulk
+	default:
+		return nil
+	}
+	w := this.waiters[priority][0]
+	this.waiters[priority] = this.waiters[priority][1:]
+	return w
+}
+
+// schedule 把空闲的客户端分配给等待的请求,调用方需要持有锁
+func (this *Pool) schedule() {
+	for len(this.idle) > 0 {
+		w := this.next()
+		if w == nil {
+			return
+		}
+		n := len(this.idle)
+		p := this.idle[n-1]
+		this.idle = this.idle[:n-1]
+		this.take(p, w.priority)
+		w.ch <- p
+	}
+}
+
+// remove 关闭并移除客户端,不足Min的在后台补上
+func (this *Pool) remove(c *Client) {
+	this.mu.Lock()
+	delete(this.all, c)
+	for i, v := range this.idle {
+		if v.Client == c {
+			this.idle = append(this.idle[:i], this.idle[i+1:]...)
+			break
+		}
+	}
+	this.mu.Unlock()
+	c.CloseAll()
+	this.fill()
+}
+
+// fill 在后台补足Min个客户端,有请求在等待时补足到Max,失败后退避重试
+func (this *Pool) fill() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	need := this.cfg.Min
+	if this.waiting() > 0 {
+		need = this.cfg.Max
+	}
+	for n := len(this.all) + this.dialing; n < need && !this.Closed(); n++ {
+		this.dialing++
+		go this.redial()
+	}
+}
+
+func (this *Pool) redial() {
+	wait := time.Second
+	for {
+		c, err := this.dial()
+		this.mu.Lock()
+		switch {
+		case this.Closed():
+			this.dialing--
+			this.mu.Unlock()
+			if err == nil {
+				c.CloseAll()
+			}
+			return
+		case err == nil:
+			this.dialing--
+			this.add(c, true)
+			this.mu.Unlock()
+			return
+		}
+		this.mu.Unlock()
+		logs.Err(err)
+		select {
+		case <-this.Done():
+		case <-time.After(wait):
+		}
+		if wait < time.Second*30 {
+			wait *= 2
+		}
+	}
+}
+
+// run 定时检查空闲的客户端
+func (this *Pool) run() {
+	t := time.NewTicker(this.cfg.CheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-this.Done():
+			return
+		case <-t.C:
+			this.check()
+		}
+	}
+}
+
+// check 检查空闲的客户端,检查期间从空闲中取出,失效的替换,超过Min且长时间没用的关闭
+func (this *Pool) check() {
+	this.mu.Lock()
+	ls := this.idle
+	this.idle = nil
+	this.mu.Unlock()
+
+	wg := sync.WaitGroup{}
+	result := make([]bool, len(ls))
+	for i, p := range ls {
+		wg.Add(1)
+		go func(i int, p *pooled) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), this.cfg.PingTimeout)
+			defer cancel()
+			latency, err := p.Ping(ctx)
+			if err != nil {
+				logs.Errf("[%s] 检查失败: %v\n", p.GetKey(), err)
+				return
+			}
+			atomic.StoreInt64(&p.latency, int64(latency))
+			result[i] = true
+		}(i, p)
+	}
+	wg.Wait()
+
+	this.mu.Lock()
+	remove := []*Client(nil)
+	for i, p := range ls {
+		switch {
+		case this.all[p.Client] != p:
+			//检查期间连接池已关闭
+			remove = append(remove, p.Client)
+		case !result[i]:
+			remove = append(remove, p.Client)
+		case len(this.all)-len(remove) > this.cfg.Min && time.Since(p.lastUsed) > this.cfg.IdleTimeout:
+			remove = append(remove, p.Client)
+		default:
+			this.idle = append(this.idle, p)
+		}
+	}
+	this.schedule()
+	this.mu.Unlock()
+
+	for _, c := range remove {
+		this.remove(c)
+	}
+	this.fill()
+}
@@ -0,0 +1,262 @@
+package tdx_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func newPool(t *testing.T, cfg *tdx.PoolConfig, fail int32) (*tdxtest.Server, *tdx.Pool) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//前fail次建立连接失败
+	var n int32
+	p, err := tdx.NewPoolWith(func() (*tdx.Client, error) {
+		if atomic.AddInt32(&n, 1) <= fail {
+			return nil, errors.New("模拟连接失败")
+		}
+		return tdx.Dial(s.Addr(), tdx.WithDebug(false))
+	}, cfg)
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		p.Close()
+		s.Close()
+	})
+	return s, p
+}
+
+func TestPool_GetContext(t *testing.T) {
+	_, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 2}, 0)
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//繁忙时扩容
+	b, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || p.Len() != 2 {
+		t.Fatalf("预期扩容到2个客户端,得到%d", p.Len())
+	}
+
+	//达到Max后等待到超时
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := p.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("预期超时,得到%v", err)
+	}
+
+	//归还后可以获取到
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		p.Put(a)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c, err := p.GetContext(ctx)
+	if err != nil || c != a {
+		t.Fatalf("预期获取到归还的客户端,得到%v", err)
+	}
+	p.Put(b)
+	p.Put(c)
+}
+
+func TestNewPoolWith(t *testing.T) {
+	//一个都连接不上时返回错误
+	_, err := tdx.NewPoolWith(func() (*tdx.Client, error) {
+		return nil, errors.New("模拟连接失败")
+	}, &tdx.PoolConfig{Min: 2, Max: 2})
+	if err == nil {
+		t.Fatal("预期返回错误")
+	}
+
+	//部分连接失败,不影响创建,在后台补上
+	_, p := newPool(t, &tdx.PoolConfig{Min: 2, Max: 2}, 1)
+	for i := 0; p.Len() < 2; i++ {
+		if i >= 50 {
+			t.Fatalf("预期在后台补上,得到%d个", p.Len())
+		}
+		<-time.After(time.Millisecond * 100)
+	}
+}
+
+func TestPool_Replace(t *testing.T) {
+	_, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 1, MaxErrors: 2}, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	a, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(a)
+
+	//连续超时达到MaxErrors后替换
+	for i := 0; i < 2; i++ {
+		p.Do(func(c *tdx.Client) error { return tdx.ErrTimeout })
+	}
+	b, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("预期替换失效的客户端")
+	}
+	if _, err := b.GetCount(protocol.ExchangeSZ); err != nil {
+		t.Fatal(err)
+	}
+	p.Put(b)
+
+	//断开的客户端归还后替换
+	b.CloseAll()
+	p.Put(b)
+	c, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == b {
+		t.Fatal("预期替换断开的客户端")
+	}
+	p.Put(c)
+
+	stats := p.Stats()
+	if len(stats) != 1 || stats[0].Errors != 0 || stats[0].Requests != 1 {
+		t.Errorf("stats: %+v", stats[0])
+	}
+}
+
+func TestPool_Check(t *testing.T) {
+	s, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 1, CheckInterval: time.Millisecond * 50, PingTimeout: time.Millisecond * 200}, 0)
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(a)
+
+	//定时检查会记录耗时
+	<-time.After(time.Millisecond * 200)
+	if stats := p.Stats(); len(stats) != 1 || stats[0].Latency <= 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	//服务器不再响应,检查失败后替换
+	block := make(chan struct{})
+	s.Handle(protocol.TypeCount, func(f *protocol.Frame) ([]byte, error) {
+		<-block
+		return nil, errors.New("关闭")
+	})
+	defer close(block)
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) {
+		c, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Put(c)
+		if c != a {
+			return
+		}
+		<-time.After(time.Millisecond * 50)
+	}
+	t.Fatal("预期替换不响应的客户端")
+}
+
+func TestPool_Priority(t *testing.T) {
+	_, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 2}, 0)
+
+	//批量任务不能使用保留的客户端
+	a, err := p.GetPriority(context.Background(), tdx.PriorityBulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := p.GetPriority(ctx, tdx.PriorityBulk); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("预期批量任务等待到超时,得到%v", err)
+	}
+	b, err := p.GetPriority(context.Background(), tdx.PriorityRealtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(a)
+	p.Put(b)
+}
+
+func TestPool_Schedule(t *testing.T) {
+	_, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 1, BulkShare: 1}, 0)
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//按顺序排队,归还后实时行情最先分配,交互请求和批量任务按比例分配
+	order := make(chan string, 4)
+	get := func(name string, priority tdx.Priority) {
+		go p.DoPriority(context.Background(), priority, func(c *tdx.Client) error {
+			order <- name
+			return nil
+		})
+		<-time.After(time.Millisecond * 20)
+	}
+	get("bulk", tdx.PriorityBulk)
+	get("interactive1", tdx.PriorityInteractive)
+	get("interactive2", tdx.PriorityInteractive)
+	get("realtime", tdx.PriorityRealtime)
+	p.Put(a)
+
+	for _, want := range []string{"realtime", "interactive1", "bulk", "interactive2"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("预期%s,得到%s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待%s超时", want)
+		}
+	}
+}
+
+func TestPool_WaitOrder(t *testing.T) {
+	_, p := newPool(t, &tdx.PoolConfig{Min: 1, Max: 1}, 0)
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//先排队的已经定时重试过扩容,后排队的还没有,归还后仍然按排队的顺序分配
+	order := make(chan string, 2)
+	get := func(name string, wait time.Duration) {
+		go p.Do(func(c *tdx.Client) error {
+			order <- name
+			return nil
+		})
+		<-time.After(wait)
+	}
+	get("first", time.Millisecond*500)
+	get("second", time.Millisecond*700)
+	p.Put(a)
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("预期%s,得到%s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待%s超时", want)
+		}
+	}
+}
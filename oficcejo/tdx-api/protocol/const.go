@@ -13,11 +13,29 @@ const (
 	TypeHistoryMinute      = 0x0FB4 //历史分时数据
 	TypeHistoryMinuteTrade = 0x0FB5 //历史分时交易
 	TypeKline              = 0x052D //K线图
+	TypeXdXr               = 0x000F //除权除息
+	TypeFinance            = 0x0010 //财务信息
+	TypeCompanyCategory    = 0x02CF //公司信息目录(F10)
+	TypeCompanyContent     = 0x02D0 //公司信息内容(F10)
+	TypeBlockMeta          = 0x02C5 //板块文件信息
+	TypeBlock              = 0x06B9 //板块文件
+	TypeAuction            = 0x056A //集合竞价
+)
+
+// 扩展行情(期货,港股,期权等)
+const (
+	TypeExConnect    = 0x2454 //建立连接
+	TypeExCount      = 0x23F0 //获取合约数量
+	TypeExMarket     = 0x23F4 //获取市场列表
+	TypeExInstrument = 0x23F5 //获取合约列表
+	TypeExQuote      = 0x23FA //行情信息
+	TypeExTrade      = 0x23FC //分笔成交
+	TypeExKline      = 0x23FF //K线图
 )
 
 var (
 	// ExchangeEstablish 交易所成立时间
-	ExchangeEstablish = time.Date(1990, 12, 19, 0, 0, 0, 0, time.Local)
+	ExchangeEstablish = time.Date(1990, 12, 19, 0, 0, 0, 0, LocationCST)
 )
 
 /*
@@ -0,0 +1,33 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrServerRejected 服务器拒绝了请求,一般是参数错误,例如代码不存在
+	ErrServerRejected = errors.New("服务器拒绝请求,请检查参数")
+
+	// ErrDecode 响应数据解析失败,原始数据见DecodeError
+	ErrDecode = errors.New("数据解析失败")
+
+	// ErrInvalidCode 代码格式错误,请求未发送
+	ErrInvalidCode = errors.New("股票代码错误")
+)
+
+// DecodeError 响应数据解析失败,携带原始数据帧,可通过errors.As获取
+type DecodeError struct {
+	Type  uint16 //响应类型
+	Frame []byte //原始数据帧
+	Err   error  //具体错误
+}
+
+func (this *DecodeError) Error() string {
+	return fmt.Sprintf("%s(0x%X): %v", ErrDecode, this.Type, this.Err)
+}
+
+// Unwrap 支持errors.Is(err,ErrDecode)以及判断具体错误
+func (this *DecodeError) Unwrap() []error {
+	return []error{ErrDecode, this.Err}
+}
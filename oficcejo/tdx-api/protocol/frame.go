@@ -14,6 +14,9 @@ const (
 	// Prefix 固定帧头
 	Prefix = 0x0C
 
+	// PrefixEx 扩展行情的请求帧头
+	PrefixEx = 0x01
+
 	// PrefixResp 响应帧头
 	PrefixResp = 0xB1CB7400
 )
@@ -65,9 +68,36 @@ func (this *Frame) Bytes() types.Bytes {
 	return data
 }
 
+// BytesEx 扩展行情的请求字节,除帧头外和Bytes一致
+func (this *Frame) BytesEx() types.Bytes {
+	data := this.Bytes()
+	data[0] = PrefixEx
+	return data
+}
+
+// DecodeFrame 解析请求帧,和Bytes互逆,兼容标准行情和扩展行情的帧头,用于模拟或回放服务器
+func DecodeFrame(bs []byte) (*Frame, error) {
+	if len(bs) < 12 {
+		return nil, errors.New("数据长度不足")
+	}
+	if bs[0] != Prefix && bs[0] != PrefixEx {
+		return nil, fmt.Errorf("帧头错误:0x%02X", bs[0])
+	}
+	length := Uint16(bs[6:8])
+	if int(length) != len(bs)-10 {
+		return nil, fmt.Errorf("数据长度不匹配,预期%d,得到%d", length+10, len(bs))
+	}
+	return &Frame{
+		MsgID:   Uint32(bs[1:5]),
+		Control: Control(bs[5]),
+		Type:    Uint16(bs[10:12]),
+		Data:    bs[12:],
+	}, nil
+}
+
 type Response struct {
 	Prefix    uint32 //未知,猜测是帧头
-	Control   uint8  //响应的控制码,目前发现0c是错误,1c是成功,猜测左数右第4位代表是否成功
+	Control   uint8  //响应的控制码,目前发现0c且无数据是错误,1c是成功,0c的行情数据也正常
 	MsgID     uint32 //消息ID
 	Unknown   uint8  //未知,猜测是响应的控制码
 	Type      uint16 //响应类型,对应请求类型,如建立连接，请求分时数据等
@@ -77,7 +107,7 @@ type Response struct {
 }
 
 /*
-Decode
+Decode 解析响应帧,帧头完整但后续解析失败时,也会返回resp,便于根据消息ID通知等待的请求
 帧头		|控制码  	|消息ID    	|控制码   	|数据类型   	|未解压长度  	|解压长度   	|数据域
 b1cb7400 	|1c   	|00000000 	|00      	|0d00       |5100      		|bd00     	|789c6378c1cecb252ace6066c5b4898987b9050ed1f90cc5b74c18a5bc18c1b43490fecff09c81819191f13fc3c9f3bb169f5e7dfefeb5ef57f7199a305009308208e5b32bb6bcbf70148712002d7f1e13
 */
@@ -96,29 +126,30 @@ func Decode(bs []byte) (*Response, error) {
 		Data:      bs[16:],
 	}
 
-	if resp.Control&0x10 != 0x10 {
-		//return nil, fmt.Errorf("请求失败,请检查参数")
+	//控制码0c的响应也有正常数据(例如未压缩的行情),所以只有0c且无数据时才认为是服务器拒绝
+	if resp.Control&0x10 != 0x10 && resp.Length == 0 {
+		return resp, ErrServerRejected
 	}
 
 	if int(resp.ZipLength) != len(bs[16:]) {
-		return nil, fmt.Errorf("压缩数据长度不匹配,预期%d,得到%d", resp.ZipLength+16, len(bs))
+		return resp, fmt.Errorf("压缩数据长度不匹配,预期%d,得到%d", resp.ZipLength+16, len(bs))
 	}
 
 	//进行数据解压
 	if resp.ZipLength != resp.Length {
 		r, err := zlib.NewReader(bytes.NewReader(resp.Data))
 		if err != nil {
-			return nil, err
+			return resp, err
 		}
 		defer r.Close()
 		resp.Data, err = io.ReadAll(r)
 		if err != nil {
-			return nil, err
+			return resp, err
 		}
 	}
 
 	if int(resp.Length) != len(resp.Data) {
-		return nil, fmt.Errorf("解压数据长度不匹配,预期%d,得到%d", resp.Length, len(resp.Data))
+		return resp, fmt.Errorf("解压数据长度不匹配,预期%d,得到%d", resp.Length, len(resp.Data))
 	}
 
 	return resp, nil
@@ -162,3 +193,23 @@ func ReadFrom(r io.Reader) (result []byte, err error) {
 	}
 
 }
+
+// ReadRequestFrom 读取一个完整的请求帧,用于模拟或回放服务器
+func ReadRequestFrom(r io.Reader) ([]byte, error) {
+	result := make([]byte, 12)
+	if _, err := io.ReadFull(r, result); err != nil {
+		return nil, err
+	}
+	if result[0] != Prefix && result[0] != PrefixEx {
+		return nil, fmt.Errorf("帧头错误:0x%02X", result[0])
+	}
+	length := Uint16(result[6:8])
+	if length < 2 {
+		return nil, errors.New("数据长度错误")
+	}
+	buf := make([]byte, length-2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return append(result, buf...), nil
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"testing"
 )
 
@@ -82,3 +83,47 @@ func TestReadFrom(t *testing.T) {
 	t.Log(hex.EncodeToString(resp.Data))
 	t.Log(string(resp.Data))
 }
+
+func TestDecode_Rejected(t *testing.T) {
+	bs, _ := hex.DecodeString("b1cb74000c0300000000500400000000")
+	resp, err := Decode(bs)
+	if !errors.Is(err, ErrServerRejected) {
+		t.Errorf("预期服务器拒绝,得到%v", err)
+		return
+	}
+	if resp == nil || resp.MsgID != 3 {
+		t.Errorf("预期返回帧头,用于通知等待的请求")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	var err error = &DecodeError{Type: TypeQuote, Frame: []byte{0x01}, Err: errors.New("数据长度不足")}
+	if !errors.Is(err, ErrDecode) {
+		t.Error("预期是ErrDecode")
+	}
+	e := &DecodeError{}
+	if !errors.As(err, &e) || len(e.Frame) != 1 {
+		t.Error("预期能获取原始数据")
+	}
+	t.Log(err)
+}
+
+func TestDecodeFrame(t *testing.T) {
+	f, err := MKline.Frame(TypeKlineDay, "sz000001", 10, 800)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f.MsgID = 9
+	got, err := DecodeFrame(f.Bytes())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got.MsgID != 9 || got.Type != TypeKline || got.Control != Control01 || !bytes.Equal(got.Data, f.Data) {
+		t.Errorf("预期%v,得到%v", f, got)
+	}
+	if _, err = DecodeFrame(f.Bytes()[:20]); err == nil {
+		t.Error("预期数据长度不匹配")
+	}
+}
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type AuctionResp struct {
+	Count uint16
+	List  []*Auction
+}
+
+// Auction 集合竞价,9:15-9:25的虚拟撮合数据
+type Auction struct {
+	Time      time.Time //时间,精确到秒
+	Price     Price     //虚拟匹配价格
+	Match     int64     //虚拟匹配量(股)
+	Unmatched int64     //未匹配量(股),正数是买盘未匹配,负数是卖盘未匹配
+}
+
+func (this *Auction) String() string {
+	return fmt.Sprintf("%s \t%-6s \t匹配量:%-8d \t未匹配量:%-8d", this.Time.Format("15:04:05"), this.Price, this.Match, this.Unmatched)
+}
+
+// IsBuy 未匹配的是否是买盘
+func (this *Auction) IsBuy() bool {
+	return this.Unmatched > 0
+}
+
+type auction struct{}
+
+/*
+Frame
+00 交易所
+00
+303030303031 代码
+00000000 起始位置
+03000100 未知,固定
+00000000 未知
+f4010000 数量,最多500条
+*/
+func (auction) Frame(code string) (*Frame, error) {
+	exchange, number, err := DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte{exchange.Uint8(), 0x0}
+	data = append(data, []byte(number)...)
+	data = append(data, 0x00, 0x00, 0x00, 0x00)
+	data = append(data, 0x03, 0x00, 0x01, 0x00)
+	data = append(data, 0x00, 0x00, 0x00, 0x00)
+	data = append(data, Bytes(uint32(500))...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeAuction,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+前2字节是数量,每条数据16字节
+2字节 时分(从0点开始的分钟数)
+4字节 价格(float32)
+4字节 匹配量
+4字节 未匹配量,有符号
+1字节 未知
+1字节 秒
+*/
+func (auction) Decode(bs []byte, c AuctionCache) (*AuctionResp, error) {
+	date, err := time.ParseInLocation("20060102", c.Date, LocationCST)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(bs)
+	resp := &AuctionResp{
+		Count: r.Uint16(),
+	}
+
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		n := r.Uint16()
+		price := r.Float32()
+		a := &Auction{
+			Price:     Price(math.Round(price * 1000)),
+			Match:     int64(r.Uint32()),
+			Unmatched: int64(int32(r.Uint32())),
+		}
+		r.Skip(1)
+		a.Time = time.Date(date.Year(), date.Month(), date.Day(), int(n/60), int(n%60), int(r.Uint8()), 0, date.Location())
+		resp.List = append(resp.List, a)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type AuctionCache struct {
+	Date string //日期,响应数据只有时间
+}
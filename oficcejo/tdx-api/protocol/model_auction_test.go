@@ -0,0 +1,54 @@
+package protocol
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_auction_Frame(t *testing.T) {
+	f, err := MAuction.Frame("sz000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if len(f.Data) != 24 {
+		t.Errorf("预期数据长度24,得到%d", len(f.Data))
+	}
+}
+
+func Test_auction_Decode(t *testing.T) {
+	bs := Bytes(uint16(2))
+	item := func(minute uint16, price float32, match uint32, unmatched int32, second uint8) []byte {
+		b := Bytes(minute)
+		b = append(b, Bytes(math.Float32bits(price))...)
+		b = append(b, Bytes(match)...)
+		b = append(b, Bytes(uint32(unmatched))...)
+		return append(b, 0x00, second)
+	}
+	bs = append(bs, item(9*60+15, 10.5, 1200, 300, 3)...)
+	bs = append(bs, item(9*60+24, 10.48, 5600, -800, 57)...)
+
+	resp, err := MAuction.Decode(bs, AuctionCache{Date: "20250102"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, v := range resp.List {
+		t.Log(v)
+	}
+	if len(resp.List) != 2 {
+		t.Fatalf("预期2条,得到%d", len(resp.List))
+	}
+	a, b := resp.List[0], resp.List[1]
+	if a.Time.Format("2006-01-02 15:04:05") != "2025-01-02 09:15:03" || a.Price != 10500 || a.Match != 1200 || !a.IsBuy() {
+		t.Errorf("第1条解析错误:%v", a)
+	}
+	if b.Time.Format("15:04:05") != "09:24:57" || b.Price != 10480 || b.Unmatched != -800 || b.IsBuy() {
+		t.Errorf("第2条解析错误:%v", b)
+	}
+
+	if _, err := MAuction.Decode(bs[:20], AuctionCache{Date: "20250102"}); err == nil {
+		t.Error("预期数据长度不足")
+	}
+}
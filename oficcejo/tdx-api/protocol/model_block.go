@@ -0,0 +1,194 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	BlockFileDefault = "block.dat"    //一般板块
+	BlockFileIndex   = "block_zs.dat" //指数板块,指数成分股
+	BlockFileStyle   = "block_fg.dat" //风格板块
+	BlockFileConcept = "block_gn.dat" //概念板块
+
+	BlockFileIndustry     = "tdxhy.cfg" //通达信行业分类,每行一只股票的行业代码
+	BlockFileIndustryName = "incon.dat" //行业等代码对应的名称
+
+	// BlockChunkSize 板块文件单次下载的最大长度
+	BlockChunkSize = 30000
+)
+
+type BlockMetaResp struct {
+	Size uint32 //文件大小
+	Hash string //文件hash,文件内容变化时会改变
+}
+
+type blockMeta struct{}
+
+// Frame 0c39186900012a002a00c502 626c6f636b5f7a732e646174...(40字节文件名)
+func (blockMeta) Frame(filename string) (*Frame, error) {
+	if len(filename) > 40 {
+		return nil, errors.New("文件名长度不能超过40")
+	}
+	data := make([]byte, 40)
+	copy(data, filename)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeBlockMeta,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+xxxxxxxx 文件大小
+00 未知
+32字节 文件hash
+00 未知
+*/
+func (blockMeta) Decode(bs []byte) (*BlockMetaResp, error) {
+	r := newReader(bs)
+	resp := &BlockMetaResp{Size: r.Uint32()}
+	r.Skip(1)
+	resp.Hash = string(cutZero(r.Bytes(32)))
+	r.Skip(1)
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type BlockResp struct {
+	Data []byte //文件内容,分段下载,需要拼接
+}
+
+type block struct{}
+
+// Frame 0c37186a00016e006e00b906 起始位置 长度 文件名(100字节)
+func (block) Frame(filename string, start, size uint32) (*Frame, error) {
+	if len(filename) > 100 {
+		return nil, errors.New("文件名长度不能超过100")
+	}
+	data := Bytes(start)
+	data = append(data, Bytes(size)...)
+	name := make([]byte, 100)
+	copy(name, filename)
+	data = append(data, name...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeBlock,
+		Data:    data,
+	}, nil
+}
+
+// Decode 前4字节未知,后续是文件内容
+func (block) Decode(bs []byte) (*BlockResp, error) {
+	r := newReader(bs)
+	r.Skip(4)
+	resp := &BlockResp{Data: r.Rest()}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Block 板块
+type Block struct {
+	Name  string   //板块名称,例白酒
+	Type  uint16   //板块类型,通达信定义,含义未知
+	Codes []string //成分股代码,不带交易所前缀,例000001
+}
+
+func (this *Block) String() string {
+	return fmt.Sprintf("%s(%d只)", this.Name, len(this.Codes))
+}
+
+/*
+DecodeBlockFile 解析板块文件,例block_zs.dat
+前384字节是文件头
+2字节板块数量
+每个板块固定2813字节:
+板块名称 9字节
+成分股数量 2字节
+板块类型 2字节
+成分股 400*7字节,每个代码7字节,不足用0x00填充
+*/
+func DecodeBlockFile(bs []byte) ([]*Block, error) {
+	if len(bs) < 386 {
+		return nil, errors.New("数据长度不足")
+	}
+	number := Uint16(bs[384:386])
+	bs = bs[386:]
+	ls := make([]*Block, 0, number)
+	for i := uint16(0); i < number; i++ {
+		if len(bs) < 2813 {
+			return nil, errors.New("数据长度不足")
+		}
+		b := &Block{
+			Name: string(UTF8ToGBK(cutZero(bs[:9]))),
+			Type: Uint16(bs[11:13]),
+		}
+		count := int(Uint16(bs[9:11]))
+		if count > 400 {
+			return nil, fmt.Errorf("板块[%s]成分股数量错误:%d", b.Name, count)
+		}
+		for j := 0; j < count; j++ {
+			code := string(cutZero(bs[13+j*7 : 13+j*7+7]))
+			if len(code) > 0 {
+				b.Codes = append(b.Codes, code)
+			}
+		}
+		ls = append(ls, b)
+		bs = bs[2813:]
+	}
+	return ls, nil
+}
+
+/*
+DecodeIndustryFile 解析行业分类,按通达信行业分组,names(incon.dat)为空时板块名称使用行业代码
+tdxhy.cfg 每行一只股票,字段用|分隔: 市场|代码|通达信行业代码|...,例 0|000001|T1001|||X500102
+incon.dat 按#开头的分类分段,通达信行业在#TDXNHY段,每行 行业代码|名称,例 T1001|银行,######结束
+*/
+func DecodeIndustryFile(hy, names []byte) ([]*Block, error) {
+	if len(hy) == 0 {
+		return nil, errors.New("数据长度不足")
+	}
+
+	nameMap := make(map[string]string)
+	section := ""
+	for _, line := range strings.Split(string(UTF8ToGBK(names)), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#"):
+			section = strings.Trim(line, "#")
+		case section == "TDXNHY":
+			if ls := strings.SplitN(line, "|", 2); len(ls) == 2 {
+				nameMap[ls[0]] = ls[1]
+			}
+		}
+	}
+
+	ls := []*Block(nil)
+	blocks := make(map[string]*Block)
+	for _, line := range strings.Split(string(UTF8ToGBK(hy)), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) < 3 || len(fields[1]) != 6 || fields[2] == "" {
+			continue
+		}
+		b := blocks[fields[2]]
+		if b == nil {
+			b = &Block{Name: fields[2]}
+			if name := nameMap[fields[2]]; name != "" {
+				b.Name = name
+			}
+			blocks[fields[2]] = b
+			ls = append(ls, b)
+		}
+		b.Codes = append(b.Codes, fields[1])
+	}
+	if len(ls) == 0 {
+		return nil, errors.New("没有解析到行业分类")
+	}
+	return ls, nil
+}
@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func Test_blockMeta_Frame(t *testing.T) {
+	f, err := MBlockMeta.Frame(BlockFileIndex)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if len(f.Data) != 40 {
+		t.Errorf("预期数据长度40,得到%d", len(f.Data))
+	}
+}
+
+func Test_block_Frame(t *testing.T) {
+	f, err := MBlock.Frame(BlockFileIndex, 0, BlockChunkSize)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if len(f.Data) != 108 {
+		t.Errorf("预期数据长度108,得到%d", len(f.Data))
+	}
+}
+
+func TestDecodeBlockFile(t *testing.T) {
+	bs := make([]byte, 384)
+	bs = append(bs, Bytes(uint16(1))...)
+	item := make([]byte, 2813)
+	copy(item, []byte{0xb0, 0xd7, 0xbe, 0xc6}) //白酒
+	copy(item[9:], Bytes(uint16(2)))
+	copy(item[11:], Bytes(uint16(2)))
+	copy(item[13:], "600519")
+	copy(item[20:], "000858")
+	bs = append(bs, item...)
+
+	ls, err := DecodeBlockFile(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, v := range ls {
+		t.Log(v, v.Codes)
+	}
+	if len(ls) != 1 || ls[0].Name != "白酒" || len(ls[0].Codes) != 2 || ls[0].Codes[1] != "000858" {
+		t.Error("解析错误")
+	}
+}
+
+func TestDecodeIndustryFile(t *testing.T) {
+	hy := []byte("0|000001|T1001|||X500102\r\n1|600519|T0401|||X340101\r\n0|000858|T0401|||X340101\r\n1|600000|T1001|||X500102\r\n")
+	names := []byte("#TDXNHY\r\nT04|\xca\xb3\xc6\xb7\xd2\xfb\xc1\xcf\r\nT0401|\xb0\xd7\xbe\xc6\r\n######\r\n#SWHY\r\nT1001|\xcd\xa8\xd0\xc5\r\n######\r\n")
+
+	ls, err := DecodeIndustryFile(hy, names)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, v := range ls {
+		t.Log(v, v.Codes)
+	}
+	if len(ls) != 2 {
+		t.Fatalf("预期2个行业,得到%d", len(ls))
+	}
+	//T1001只在其他分类中有名称,使用行业代码
+	if ls[0].Name != "T1001" || len(ls[0].Codes) != 2 || ls[0].Codes[1] != "600000" {
+		t.Errorf("解析错误: %v %v", ls[0], ls[0].Codes)
+	}
+	if ls[1].Name != "白酒" || len(ls[1].Codes) != 2 || ls[1].Codes[0] != "600519" {
+		t.Errorf("解析错误: %v %v", ls[1], ls[1].Codes)
+	}
+}
@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -34,28 +33,26 @@ func (code) Frame(exchange Exchange, start uint16) *Frame {
 
 func (code) Decode(bs []byte) (*CodeResp, error) {
 
-	if len(bs) < 2 {
-		return nil, errors.New("数据长度不足")
-	}
-
+	r := newReader(bs)
 	resp := &CodeResp{
-		Count: Uint16(bs[:2]),
+		Count: r.Uint16(),
 	}
-	bs = bs[2:]
 
-	for i := uint16(0); i < resp.Count; i++ {
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
 		sec := &Code{
-			Code:      string(bs[:6]),
-			Multiple:  Uint16(bs[6:8]),
-			Name:      string(UTF8ToGBK(bs[8:16])),
-			Decimal:   int8(bs[20]),
-			LastPrice: getVolume2(Uint32(bs[21:25])),
+			Code:     string(r.Bytes(6)),
+			Multiple: r.Uint16(),
+			Name:     r.GBK(8),
 		}
-		//logs.Debug(bs[25:29]) //26和28字节 好像是枚举(基本是44,45和34,35)
-		bs = bs[29:]
+		r.Skip(4)
+		sec.Decimal = int8(r.Uint8())
+		sec.LastPrice = getVolume2(r.Uint32())
+		r.Skip(4) //26和28字节 好像是枚举(基本是44,45和34,35)
 		resp.List = append(resp.List, sec)
 	}
 
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
 	return resp, nil
-
 }
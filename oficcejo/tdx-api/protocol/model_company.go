@@ -0,0 +1,137 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+type CompanyCategoryResp struct {
+	Count uint16
+	List  []*CompanyCategory
+}
+
+// CompanyCategory F10公司信息目录,例如公司概况,股东研究等
+type CompanyCategory struct {
+	Name     string //名称,例最新提示
+	Filename string //文件名,例000001.txt
+	Start    uint32 //在文件中的起始位置
+	Length   uint32 //内容长度
+}
+
+func (this *CompanyCategory) String() string {
+	return fmt.Sprintf("%s(%s) %d-%d", this.Name, this.Filename, this.Start, this.Start+this.Length)
+}
+
+type companyCategory struct{}
+
+// Frame 0c0f109b00010e000e00cf02 0000 303030303031 00000000
+func (companyCategory) Frame(code string) (*Frame, error) {
+	exchange, number, err := DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte{exchange.Uint8(), 0x0}
+	data = append(data, []byte(number)...)
+	data = append(data, 0x0, 0x0, 0x0, 0x0)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeCompanyCategory,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+前2字节是数量,每条数据152字节
+
+名称 64字节
+文件名 80字节
+起始位置 4字节
+长度 4字节
+*/
+func (companyCategory) Decode(bs []byte) (*CompanyCategoryResp, error) {
+	r := newReader(bs)
+	resp := &CompanyCategoryResp{
+		Count: r.Uint16(),
+	}
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		resp.List = append(resp.List, &CompanyCategory{
+			Name:     r.GBK(64),
+			Filename: r.GBK(80),
+			Start:    r.Uint32(),
+			Length:   r.Uint32(),
+		})
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type CompanyContentResp struct {
+	Exchange Exchange //市场
+	Code     string   //代码
+	Length   uint16   //内容长度
+	Content  string   //内容,已转成UTF8
+}
+
+type companyContent struct{}
+
+// Frame 0c07109c000168006800d002 0000 303030303031 0000 文件名(80字节) 起始位置 长度 00000000
+func (companyContent) Frame(code, filename string, start, length uint32) (*Frame, error) {
+	exchange, number, err := DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(filename) > 80 {
+		return nil, errors.New("文件名长度不能超过80")
+	}
+	data := []byte{exchange.Uint8(), 0x0}
+	data = append(data, []byte(number)...)
+	data = append(data, 0x0, 0x0)
+	name := make([]byte, 80)
+	copy(name, filename)
+	data = append(data, name...)
+	data = append(data, Bytes(start)...)
+	data = append(data, Bytes(length)...)
+	data = append(data, 0x0, 0x0, 0x0, 0x0)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeCompanyContent,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+0000 市场
+303030303031 代码
+0000 未知
+xxxx 内容长度
+后续是GBK编码的内容
+*/
+func (companyContent) Decode(bs []byte) (*CompanyContentResp, error) {
+	r := newReader(bs)
+	resp := &CompanyContentResp{
+		Exchange: Exchange(r.Uint8()),
+	}
+	r.Skip(1)
+	resp.Code = string(r.Bytes(6))
+	r.Skip(2)
+	resp.Length = r.Uint16()
+	content := r.Bytes(int(resp.Length))
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	resp.Content = string(UTF8ToGBK(content))
+	return resp, nil
+}
+
+// cutZero 截取到第一个0x00
+func cutZero(bs []byte) []byte {
+	if i := bytes.IndexByte(bs, 0x00); i >= 0 {
+		return bs[:i]
+	}
+	return bs
+}
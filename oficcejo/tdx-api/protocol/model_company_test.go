@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_companyCategory_Frame(t *testing.T) {
+	//预期0c00000000010e000e00cf02000030303030303100000000
+	f, err := MCompanyCategory.Frame("sz000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if f.Bytes().HEX() != "0c00000000010e000e00cf02000030303030303100000000" {
+		t.Error("编码错误")
+	}
+}
+
+func Test_companyCategory_Decode(t *testing.T) {
+	s := "0100d7eed0c2cce1cabe00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000003030303030312e747874000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000081240000"
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err := MCompanyCategory.Decode(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, v := range resp.List {
+		t.Log(v)
+	}
+	if len(resp.List) != 1 || resp.List[0].Name != "最新提示" || resp.List[0].Filename != "000001.txt" || resp.List[0].Length != 9345 {
+		t.Error("解析错误")
+	}
+}
+
+func Test_companyContent_Frame(t *testing.T) {
+	f, err := MCompanyContent.Frame("sz000001", "000001.txt", 0, 9345)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if len(f.Data) != 102 {
+		t.Errorf("预期数据长度102,得到%d", len(f.Data))
+	}
+}
+
+func Test_companyContent_Decode(t *testing.T) {
+	bs, err := hex.DecodeString("000030303030303100000800c6bdb0b2d2f8d0d0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err := MCompanyContent.Decode(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(resp.Content)
+	if resp.Content != "平安银行" {
+		t.Error("解析错误")
+	}
+}
@@ -1,20 +1,32 @@
 package protocol
 
-import (
-	"errors"
-)
+import ()
 
 var (
-	MConnect       = connect{}
-	MHeart         = heart{}
-	MCount         = count{}
-	MQuote         = quote{}
-	MCode          = code{}
-	MMinute        = minute{}
-	MHistoryMinute = historyMinute{}
-	MTrade         = trade{}
-	MHistoryTrade  = historyTrade{}
-	MKline         = kline{}
+	MConnect         = connect{}
+	MHeart           = heart{}
+	MCount           = count{}
+	MQuote           = quote{}
+	MCode            = code{}
+	MMinute          = minute{}
+	MHistoryMinute   = historyMinute{}
+	MTrade           = trade{}
+	MHistoryTrade    = historyTrade{}
+	MKline           = kline{}
+	MXdXr            = xdxr{}
+	MFinance         = finance{}
+	MCompanyCategory = companyCategory{}
+	MCompanyContent  = companyContent{}
+	MBlockMeta       = blockMeta{}
+	MBlock           = block{}
+	MAuction         = auction{}
+	MExConnect       = exConnect{}
+	MExCount         = exCount{}
+	MExMarket        = exMarket{}
+	MExInstrument    = exInstrument{}
+	MExQuote         = exQuote{}
+	MExTrade         = exTrade{}
+	MExKline         = exKline{}
 )
 
 type ConnectResp struct {
@@ -32,11 +44,13 @@ func (connect) Frame() *Frame {
 }
 
 func (connect) Decode(bs []byte) (*ConnectResp, error) {
-	if len(bs) < 68 {
-		return nil, errors.New("数据长度不足")
+	r := newReader(bs)
+	r.Skip(68) //前68字节暂时还不知道是什么
+	info := r.Rest()
+	if err := r.Err(); err != nil {
+		return nil, err
 	}
-	//前68字节暂时还不知道是什么
-	return &ConnectResp{Info: string(UTF8ToGBK(bs[68:]))}, nil
+	return &ConnectResp{Info: string(UTF8ToGBK(info))}, nil
 }
 
 /*
@@ -1,7 +1,5 @@
 package protocol
 
-import "errors"
-
 type CountResp struct {
 	Count uint16
 }
@@ -18,8 +16,10 @@ func (this *count) Frame(exchange Exchange) *Frame {
 }
 
 func (this *count) Decode(bs []byte) (*CountResp, error) {
-	if len(bs) < 2 {
-		return nil, errors.New("数据长度不足")
+	r := newReader(bs)
+	resp := &CountResp{Count: r.Uint16()}
+	if err := r.Err(); err != nil {
+		return nil, err
 	}
-	return &CountResp{Count: Uint16(bs)}, nil
+	return resp, nil
 }
@@ -0,0 +1,499 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+扩展行情(期货,港股,期权等),服务器端口一般是7727
+请求帧的帧头是0x01,其余结构和标准行情一致,响应帧的结构和标准行情一致
+市场和代码的编码方式不同,市场是1字节,代码是9字节,不足用0x00填充
+*/
+
+// ExMarket 扩展行情的市场
+type ExMarket uint8
+
+func (this ExMarket) Uint8() uint8 { return uint8(this) }
+
+func (this ExMarket) String() string {
+	switch this {
+	case ExMarketSHOption:
+		return "上海个股期权"
+	case ExMarketSZOption:
+		return "深圳个股期权"
+	case ExMarketCZCE:
+		return "郑州商品"
+	case ExMarketDCE:
+		return "大连商品"
+	case ExMarketSHFE:
+		return "上海期货"
+	case ExMarketHK:
+		return "香港主板"
+	case ExMarketCFFEX:
+		return "中金所期货"
+	case ExMarketHKGEM:
+		return "香港创业板"
+	case ExMarketUS:
+		return "美国股票"
+	default:
+		return fmt.Sprintf("市场%d", uint8(this))
+	}
+}
+
+const (
+	ExMarketSHOption ExMarket = 8  //上海个股期权,例50ETF期权
+	ExMarketSZOption ExMarket = 9  //深圳个股期权
+	ExMarketCZCE     ExMarket = 28 //郑州商品期货
+	ExMarketDCE      ExMarket = 29 //大连商品期货
+	ExMarketSHFE     ExMarket = 30 //上海期货
+	ExMarketHK       ExMarket = 31 //香港主板
+	ExMarketCFFEX    ExMarket = 47 //中金所期货,例IF,IC
+	ExMarketHKGEM    ExMarket = 48 //香港创业板
+	ExMarketUS       ExMarket = 74 //美国股票
+)
+
+// exCode 扩展行情的代码,9字节,不足用0x00填充
+func exCode(market ExMarket, code string) ([]byte, error) {
+	if len(code) == 0 || len(code) > 9 {
+		return nil, fmt.Errorf("%w[%s],长度应为1-9", ErrInvalidCode, code)
+	}
+	data := make([]byte, 10)
+	data[0] = market.Uint8()
+	copy(data[1:], code)
+	return data, nil
+}
+
+// exPrice 扩展行情的价格是float32,转成厘
+func exPrice(bs []byte) Price {
+	return Price(math.Round(float64(math.Float32frombits(Uint32(bs))) * 1000))
+}
+
+func (this *reader) exPrice() Price {
+	return exPrice(this.Bytes(4))
+}
+
+type exConnect struct{}
+
+// Frame 010148650001520052005424 ...(80字节固定数据)
+func (exConnect) Frame() *Frame {
+	bs := []byte{
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0x1f, 0x32, 0xc6, 0xe5, 0xd5, 0x3d, 0xfb, 0x41,
+		0xcc, 0xe1, 0x6d, 0xff, 0xd5, 0xba, 0x3f, 0xb8,
+		0xcb, 0xc5, 0x7a, 0x05, 0x4f, 0x77, 0x48, 0xea,
+	}
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExConnect,
+		Data:    bs,
+	}
+}
+
+type ExMarketResp struct {
+	Count uint16
+	List  []*ExMarketInfo
+}
+
+// ExMarketInfo 扩展行情的市场信息
+type ExMarketInfo struct {
+	Category  uint8    //分类
+	Name      string   //名称,例中金所期货
+	Market    ExMarket //市场
+	ShortName string   //简称,例CZ
+}
+
+func (this *ExMarketInfo) String() string {
+	return fmt.Sprintf("%d %s(%s) 分类:%d", this.Market, this.Name, this.ShortName, this.Category)
+}
+
+type exMarket struct{}
+
+// Frame 01024869000102000200f423
+func (exMarket) Frame() *Frame {
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExMarket,
+	}
+}
+
+/*
+Decode
+前2字节是数量,每条数据64字节
+分类 1字节
+名称 32字节
+市场 1字节
+简称 2字节
+未知 28字节
+*/
+func (exMarket) Decode(bs []byte) (*ExMarketResp, error) {
+	r := newReader(bs)
+	resp := &ExMarketResp{
+		Count: r.Uint16(),
+	}
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		resp.List = append(resp.List, &ExMarketInfo{
+			Category:  r.Uint8(),
+			Name:      r.GBK(32),
+			Market:    ExMarket(r.Uint8()),
+			ShortName: string(cutZero(r.Bytes(2))),
+		})
+		r.Skip(28)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ExCountResp struct {
+	Count uint32
+}
+
+type exCount struct{}
+
+// Frame 01034866000102000200f023
+func (exCount) Frame() *Frame {
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExCount,
+	}
+}
+
+// Decode 前19字节未知,后4字节是数量
+func (exCount) Decode(bs []byte) (*ExCountResp, error) {
+	r := newReader(bs)
+	r.Skip(19)
+	resp := &ExCountResp{Count: r.Uint32()}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ExInstrumentResp struct {
+	Start uint32
+	Count uint16
+	List  []*ExInstrument
+}
+
+// ExInstrument 扩展行情的合约/证券
+type ExInstrument struct {
+	Category uint8    //分类
+	Market   ExMarket //市场
+	Code     string   //代码,例IF2412
+	Name     string   //名称,例沪深2412
+	Desc     string   //描述
+}
+
+func (this *ExInstrument) String() string {
+	return fmt.Sprintf("%d %s %s", this.Market, this.Code, this.Name)
+}
+
+type exInstrument struct{}
+
+// Frame 01044867000108000800f523 起始位置(4字节) 数量(2字节)
+func (exInstrument) Frame(start uint32, count uint16) *Frame {
+	data := Bytes(start)
+	data = append(data, Bytes(count)...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExInstrument,
+		Data:    data,
+	}
+}
+
+/*
+Decode
+起始位置 4字节
+数量 2字节
+每条数据64字节
+分类 1字节
+市场 1字节
+未知 3字节
+代码 9字节
+名称 17字节
+描述 9字节
+未知 24字节
+*/
+func (exInstrument) Decode(bs []byte) (*ExInstrumentResp, error) {
+	r := newReader(bs)
+	resp := &ExInstrumentResp{
+		Start: r.Uint32(),
+		Count: r.Uint16(),
+	}
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		v := &ExInstrument{
+			Category: r.Uint8(),
+			Market:   ExMarket(r.Uint8()),
+		}
+		r.Skip(3)
+		v.Code = r.GBK(9)
+		v.Name = r.GBK(17)
+		v.Desc = r.GBK(9)
+		r.Skip(24)
+		resp.List = append(resp.List, v)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExQuote 扩展行情的盘口
+type ExQuote struct {
+	Market       ExMarket
+	Code         string
+	Last         Price //昨收(结算)价
+	Open         Price //开盘价
+	High         Price //最高价
+	Low          Price //最低价
+	Price        Price //最新价
+	OpenPosition int64 //开仓量
+	TotalVolume  int64 //总量
+	Volume       int64 //现量
+	Inside       int64 //内盘
+	Outside      int64 //外盘
+	Position     int64 //持仓量
+	BuyLevel     PriceLevels
+	SellLevel    PriceLevels
+}
+
+func (this *ExQuote) String() string {
+	return fmt.Sprintf("%d %s 最新价:%s 开盘价:%s 最高价:%s 最低价:%s 昨收:%s 总量:%d 持仓:%d\n%s%s",
+		this.Market, this.Code, this.Price, this.Open, this.High, this.Low, this.Last,
+		this.TotalVolume, this.Position, this.SellLevel, this.BuyLevel,
+	)
+}
+
+type exQuote struct{}
+
+// Frame 0101080202010c000c00fa23 市场(1字节) 代码(9字节)
+func (exQuote) Frame(market ExMarket, code string) (*Frame, error) {
+	data, err := exCode(market, code)
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExQuote,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+市场 1字节
+代码 9字节
+未知 4字节
+昨收,开盘,最高,最低,最新 5*float32
+开仓,未知,总量,现量,未知,内盘,外盘,未知,持仓 9*uint32
+买1-5价 5*float32, 买1-5量 5*uint32
+卖1-5价 5*float32, 卖1-5量 5*uint32
+*/
+func (exQuote) Decode(bs []byte) (*ExQuote, error) {
+	r := newReader(bs)
+	resp := &ExQuote{
+		Market: ExMarket(r.Uint8()),
+		Code:   string(cutZero(r.Bytes(9))),
+	}
+	r.Skip(4)
+	resp.Last = r.exPrice()
+	resp.Open = r.exPrice()
+	resp.High = r.exPrice()
+	resp.Low = r.exPrice()
+	resp.Price = r.exPrice()
+	resp.OpenPosition = int64(r.Uint32())
+	r.Skip(4)
+	resp.TotalVolume = int64(r.Uint32())
+	resp.Volume = int64(r.Uint32())
+	r.Skip(4)
+	resp.Inside = int64(r.Uint32())
+	resp.Outside = int64(r.Uint32())
+	r.Skip(4)
+	resp.Position = int64(r.Uint32())
+	for i := 0; i < 5; i++ {
+		resp.BuyLevel[i] = PriceLevel{Buy: true, Price: r.exPrice()}
+	}
+	for i := 0; i < 5; i++ {
+		resp.BuyLevel[i].Number = int(r.Uint32())
+	}
+	for i := 0; i < 5; i++ {
+		resp.SellLevel[i] = PriceLevel{Price: r.exPrice()}
+	}
+	for i := 0; i < 5; i++ {
+		resp.SellLevel[i].Number = int(r.Uint32())
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ExKlineResp struct {
+	Count uint16
+	List  []*ExKline
+}
+
+// ExKline 扩展行情的K线
+type ExKline struct {
+	Time       time.Time //时间
+	Open       Price     //开盘价
+	High       Price     //最高价
+	Low        Price     //最低价
+	Close      Price     //收盘价
+	Position   int64     //持仓量
+	Volume     int64     //成交量
+	Settlement Price     //结算价
+}
+
+func (this *ExKline) String() string {
+	return fmt.Sprintf("%s 开盘价：%.3f 最高价：%.3f 最低价：%.3f 收盘价：%.3f 成交量：%d 持仓量：%d",
+		this.Time.Format("2006-01-02 15:04:05"),
+		this.Open.Float64(), this.High.Float64(), this.Low.Float64(), this.Close.Float64(),
+		this.Volume, this.Position,
+	)
+}
+
+type exKline struct{}
+
+// Frame 0101086a010116001600ff23 市场(1字节) 代码(9字节) 类型(2字节) 0100 起始位置(4字节) 数量(2字节)
+func (exKline) Frame(Type uint8, market ExMarket, code string, start uint32, count uint16) (*Frame, error) {
+	if count > 800 {
+		return nil, errors.New("单次数量不能超过800")
+	}
+	data, err := exCode(market, code)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, Type, 0x00)
+	data = append(data, 0x01, 0x00)
+	data = append(data, Bytes(start)...)
+	data = append(data, Bytes(count)...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExKline,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+市场 1字节
+代码 9字节
+未知 8字节
+数量 2字节
+每条数据32字节
+时间 4字节,格式和标准K线一致
+开高低收 4*float32
+持仓 4字节
+成交量 4字节
+结算价 float32
+*/
+func (exKline) Decode(bs []byte, c KlineCache) (*ExKlineResp, error) {
+	r := newReader(bs)
+	r.Skip(18)
+	resp := &ExKlineResp{
+		Count: r.Uint16(),
+	}
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		resp.List = append(resp.List, &ExKline{
+			Time:       GetTime([4]byte(r.Bytes(4)), c.Type),
+			Open:       r.exPrice(),
+			High:       r.exPrice(),
+			Low:        r.exPrice(),
+			Close:      r.exPrice(),
+			Position:   int64(r.Uint32()),
+			Volume:     int64(r.Uint32()),
+			Settlement: r.exPrice(),
+		})
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ExTradeResp struct {
+	Count uint16
+	List  []*ExTrade
+}
+
+// ExTrade 扩展行情的分笔成交
+type ExTrade struct {
+	Time      time.Time //时间,精确到分钟
+	Price     Price     //成交价,服务器返回的是整数,按厘处理
+	Volume    int64     //成交量
+	Position  int64     //增仓量,负数表示减仓
+	Direction uint16    //方向,服务器定义,含义未完全确定
+}
+
+func (this *ExTrade) String() string {
+	return fmt.Sprintf("%s \t%-6s \t%-6d(手) \t增仓:%-6d", this.Time.Format("15:04"), this.Price, this.Volume, this.Position)
+}
+
+type exTrade struct{}
+
+// Frame 01010800030112001200fc23 市场(1字节) 代码(9字节) 起始位置(4字节) 数量(2字节)
+func (exTrade) Frame(market ExMarket, code string, start uint32, count uint16) (*Frame, error) {
+	data, err := exCode(market, code)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, Bytes(start)...)
+	data = append(data, Bytes(count)...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeExTrade,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+市场 1字节
+代码 9字节
+未知 4字节
+数量 2字节
+每条数据16字节
+时分 2字节(从0点开始的分钟数)
+价格 4字节
+成交量 4字节
+增仓 4字节,有符号
+方向 2字节
+*/
+func (exTrade) Decode(bs []byte, c TradeCache) (*ExTradeResp, error) {
+	date, err := time.ParseInLocation("20060102", c.Date, LocationCST)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(bs)
+	r.Skip(14)
+	resp := &ExTradeResp{
+		Count: r.Uint16(),
+	}
+
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		n := r.Uint16()
+		resp.List = append(resp.List, &ExTrade{
+			Time:      time.Date(date.Year(), date.Month(), date.Day(), int(n/60), int(n%60), 0, 0, date.Location()),
+			Price:     Price(r.Uint32()),
+			Volume:    int64(r.Uint32()),
+			Position:  int64(int32(r.Uint32())),
+			Direction: r.Uint16(),
+		})
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+package protocol
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrame_BytesEx(t *testing.T) {
+	f := MExMarket.Frame()
+	f.MsgID = 0x00694802
+	if s := f.BytesEx().HEX(); s != "01024869000102000200f423" {
+		t.Errorf("预期01024869000102000200f423,得到%s", s)
+	}
+}
+
+func Test_exKline_Frame(t *testing.T) {
+	f, err := MExKline.Frame(TypeKlineDay, ExMarketCFFEX, "IF2412", 0, 10)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.BytesEx().HEX())
+	if len(f.Data) != 20 {
+		t.Errorf("预期数据长度20,得到%d", len(f.Data))
+	}
+	if _, err := MExKline.Frame(TypeKlineDay, ExMarketCFFEX, "IF24120000", 0, 10); err == nil {
+		t.Error("预期代码长度错误")
+	}
+}
+
+func Test_exKline_Decode(t *testing.T) {
+	f32 := func(f float32) []byte { return Bytes(math.Float32bits(f)) }
+	bs := make([]byte, 18)
+	bs = append(bs, Bytes(uint16(1))...)
+	bs = append(bs, Bytes(uint32(20241202))...)
+	bs = append(bs, f32(3900.2)...)
+	bs = append(bs, f32(3950)...)
+	bs = append(bs, f32(3880.4)...)
+	bs = append(bs, f32(3940.6)...)
+	bs = append(bs, Bytes(uint32(230000))...)
+	bs = append(bs, Bytes(uint32(85000))...)
+	bs = append(bs, f32(3938.8)...)
+
+	resp, err := MExKline.Decode(bs, KlineCache{Type: TypeKlineDay})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, v := range resp.List {
+		t.Log(v)
+	}
+	if len(resp.List) != 1 {
+		t.Fatalf("预期1条,得到%d", len(resp.List))
+	}
+	k := resp.List[0]
+	if k.Time.Format("20060102") != "20241202" || k.Open != 3900200 || k.Close != 3940600 || k.Position != 230000 || k.Volume != 85000 {
+		t.Errorf("解析错误:%v", k)
+	}
+}
+
+func Test_exInstrument_Decode(t *testing.T) {
+	bs := Bytes(uint32(0))
+	bs = append(bs, Bytes(uint16(1))...)
+	item := make([]byte, 64)
+	item[0] = 3
+	item[1] = ExMarketCFFEX.Uint8()
+	copy(item[5:], "IF2412")
+	copy(item[14:], "IF2412")
+	bs = append(bs, item...)
+
+	resp, err := MExInstrument.Decode(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(resp.List) != 1 || resp.List[0].Code != "IF2412" || resp.List[0].Market != ExMarketCFFEX {
+		t.Errorf("解析错误:%v", resp.List)
+	}
+}
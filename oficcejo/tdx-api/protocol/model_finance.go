@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"fmt"
+	"time"
+)
+
+// Finance 财务信息,股本单位股,金额单位元
+type Finance struct {
+	Exchange    Exchange  //市场
+	Code        string    //股票代码
+	FloatShares float64   //流通股本
+	Province    uint16    //所属省份代码
+	Industry    uint16    //所属行业代码
+	UpdatedDate time.Time //财务数据更新日期
+	ListingDate time.Time //上市日期
+
+	TotalShares       float64 //总股本
+	StateShares       float64 //国家股
+	PromoterShares    float64 //发起人法人股
+	LegalPersonShares float64 //法人股
+	BShares           float64 //B股
+	HShares           float64 //H股
+	StaffShares       float64 //职工股
+
+	TotalAssets         float64 //总资产
+	CurrentAssets       float64 //流动资产
+	FixedAssets         float64 //固定资产
+	IntangibleAssets    float64 //无形资产
+	Shareholders        float64 //股东人数
+	CurrentLiabilities  float64 //流动负债
+	LongTermLiabilities float64 //长期负债
+	CapitalReserve      float64 //资本公积金
+	NetAssets           float64 //净资产
+
+	MainRevenue         float64 //主营收入
+	MainProfit          float64 //主营利润
+	Receivables         float64 //应收账款
+	OperatingProfit     float64 //营业利润
+	InvestmentIncome    float64 //投资收益
+	OperatingCashFlow   float64 //经营现金流
+	TotalCashFlow       float64 //总现金流
+	Inventory           float64 //存货
+	TotalProfit         float64 //利润总额
+	ProfitAfterTax      float64 //税后利润
+	NetProfit           float64 //净利润
+	UndistributedProfit float64 //未分配利润
+	BPS                 float64 //每股净资产(元)
+	Reserved            float64 //保留,未知
+}
+
+func (this *Finance) String() string {
+	return fmt.Sprintf("%s%s 上市日期：%s 总股本：%s 流通股本：%s 总资产：%s 净利润：%s 每股收益：%.3f 每股净资产：%.3f",
+		this.Exchange.String(), this.Code, this.ListingDate.Format("2006-01-02"),
+		FloatUnitString(this.TotalShares), FloatUnitString(this.FloatShares),
+		FloatUnitString(this.TotalAssets), FloatUnitString(this.NetProfit),
+		this.EPS(), this.BPS,
+	)
+}
+
+// EPS 每股收益(元),净利润/总股本
+func (this *Finance) EPS() float64 {
+	if this.TotalShares == 0 {
+		return 0
+	}
+	return this.NetProfit / this.TotalShares
+}
+
+// TurnoverRate 换手率(%),成交量单位手
+func (this *Finance) TurnoverRate(hand int) float64 {
+	if this.FloatShares == 0 {
+		return 0
+	}
+	return float64(hand) * 100 / this.FloatShares * 100
+}
+
+// FloatMarketValue 流通市值(元)
+func (this *Finance) FloatMarketValue(price Price) float64 {
+	return this.FloatShares * price.Float64()
+}
+
+// TotalMarketValue 总市值(元)
+func (this *Finance) TotalMarketValue(price Price) float64 {
+	return this.TotalShares * price.Float64()
+}
+
+type finance struct{}
+
+// Frame 0c1f187600010b000b001000010000303030303031
+func (finance) Frame(code string) (*Frame, error) {
+	exchange, number, err := DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte{0x01, 0x00} //数量,固定1个
+	data = append(data, exchange.Uint8())
+	data = append(data, []byte(number)...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeFinance,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+0100 数量
+00 市场
+303030303031 代码
+后续是136字节的数据,float32为主,股本和金额的单位是万
+*/
+func (finance) Decode(bs []byte) (*Finance, error) {
+	r := newReader(bs)
+	r.Skip(2)
+	resp := &Finance{
+		Exchange: Exchange(r.Uint8()),
+		Code:     string(r.Bytes(6)),
+	}
+
+	resp.FloatShares = r.Float32Round() * 1e4
+	resp.Province = r.Uint16()
+	resp.Industry = r.Uint16()
+	resp.UpdatedDate = getDate(r.Uint32())
+	resp.ListingDate = getDate(r.Uint32())
+
+	fs := make([]float64, 30)
+	for i := range fs {
+		fs[i] = r.Float32Round()
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	resp.TotalShares = fs[0] * 1e4
+	resp.StateShares = fs[1] * 1e4
+	resp.PromoterShares = fs[2] * 1e4
+	resp.LegalPersonShares = fs[3] * 1e4
+	resp.BShares = fs[4] * 1e4
+	resp.HShares = fs[5] * 1e4
+	resp.StaffShares = fs[6] * 1e4
+	resp.TotalAssets = fs[7] * 1e4
+	resp.CurrentAssets = fs[8] * 1e4
+	resp.FixedAssets = fs[9] * 1e4
+	resp.IntangibleAssets = fs[10] * 1e4
+	resp.Shareholders = fs[11]
+	resp.CurrentLiabilities = fs[12] * 1e4
+	resp.LongTermLiabilities = fs[13] * 1e4
+	resp.CapitalReserve = fs[14] * 1e4
+	resp.NetAssets = fs[15] * 1e4
+	resp.MainRevenue = fs[16] * 1e4
+	resp.MainProfit = fs[17] * 1e4
+	resp.Receivables = fs[18] * 1e4
+	resp.OperatingProfit = fs[19] * 1e4
+	resp.InvestmentIncome = fs[20] * 1e4
+	resp.OperatingCashFlow = fs[21] * 1e4
+	resp.TotalCashFlow = fs[22] * 1e4
+	resp.Inventory = fs[23] * 1e4
+	resp.TotalProfit = fs[24] * 1e4
+	resp.ProfitAfterTax = fs[25] * 1e4
+	resp.NetProfit = fs[26] * 1e4
+	resp.UndistributedProfit = fs[27] * 1e4
+	resp.BPS = fs[28]
+	resp.Reserved = fs[29]
+
+	return resp, nil
+}
+
+// getDate 解析20060102格式的数字日期,0表示无效日期
+func getDate(n uint32) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Date(int(n/10000), time.Month(n%10000/100), int(n%100), 0, 0, 0, 0, LocationCST)
+}
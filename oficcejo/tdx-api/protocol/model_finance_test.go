@@ -0,0 +1,40 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_finance_Frame(t *testing.T) {
+	//预期0c00000000010b000b001000010000303030303031
+	f, err := MFinance.Frame("sz000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if f.Bytes().HEX() != "0c00000000010b000b001000010000303030303031" {
+		t.Error("编码错误")
+	}
+}
+
+func Test_finance_Decode(t *testing.T) {
+	s := "010000303030303031f8e2ec4912000100cbd7340103cf2f0180e3ec490000000000000000000000000000000000000000000000005bb5094e0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008046b649000000008fc2b34100000000"
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err := MFinance.Decode(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(resp)
+	if resp.Code != "000001" || resp.ListingDate.Format("20060102") != "19910403" || resp.Province != 18 || resp.Industry != 1 {
+		t.Error("解析错误")
+	}
+	if resp.TotalShares != 1940592*1e4 || resp.BPS != 22.47 {
+		t.Error("解析错误")
+	}
+}
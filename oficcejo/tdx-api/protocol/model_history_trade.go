@@ -1,17 +1,11 @@
 package protocol
 
 import (
-	"errors"
 	"time"
 
 	"github.com/injoyai/conv"
 )
 
-var (
-	// 中国标准时间时区 (UTC+8) - 用于历史分时成交
-	locationCSTHistory = time.FixedZone("CST", 8*3600)
-)
-
 // HistoryTradeResp 兼容之前的版本
 type HistoryTradeResp = TradeResp
 
@@ -35,40 +29,39 @@ func (historyTrade) Frame(date, code string, start, count uint16) (*Frame, error
 }
 
 func (historyTrade) Decode(bs []byte, c TradeCache) (*TradeResp, error) {
-	if len(bs) < 2 {
-		return nil, errors.New("数据长度不足")
-	}
 
-	_, number, err := DecodeCode(c.Code)
-	if err != nil {
+	if _, _, err := DecodeCode(c.Code); err != nil {
 		return nil, err
 	}
+	decimal := decimalOf(c.Code, c.Decimal)
 
+	r := newReader(bs)
 	resp := &TradeResp{
-		Count: Uint16(bs[:2]),
+		Count: r.Uint16(),
 	}
 
 	//第2-6字节不知道是啥
-	bs = bs[2+4:]
+	r.Skip(4)
 
 	lastPrice := Price(0)
-	for i := uint16(0); i < resp.Count; i++ {
-		timeStr := GetHourMinute([2]byte(bs[:2]))
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		timeStr := GetHourMinute([2]byte(r.Bytes(2)))
 		// 数据中的时间本身就是北京时间，使用CST时区解析
-		t, err := time.ParseInLocation("2006010215:04", c.Date+timeStr, locationCSTHistory)
+		t, err := time.ParseInLocation("2006010215:04", c.Date+timeStr, LocationCST)
 		if err != nil {
 			return nil, err
 		}
 		mt := &Trade{Time: t}
-		var sub Price
-		bs, sub = GetPrice(bs[2:])
-		lastPrice += sub * 10 //把分转成厘
-		mt.Price = lastPrice / basePrice(number)
-		bs, mt.Volume = CutInt(bs)
-		bs, mt.Status = CutInt(bs)
-		bs, _ = CutInt(bs) //这个得到的是0，不知道是啥
+		lastPrice += r.Price()
+		mt.Price = ScalePrice(lastPrice, decimal)
+		mt.Volume = r.Int()
+		mt.Status = r.Int()
+		r.Int() //这个得到的是0，不知道是啥
 		resp.List = append(resp.List, mt)
 	}
 
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
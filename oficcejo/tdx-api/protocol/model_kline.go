@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/injoyai/base/types"
 	"github.com/injoyai/conv"
+	"math"
 	"sort"
 	"time"
 )
@@ -21,7 +22,7 @@ func (this *KlineReq) Bytes(Type uint8) (types.Bytes, error) {
 		return nil, errors.New("单次数量不能超过800")
 	}
 	if len(this.Code) != 6 {
-		return nil, errors.New("股票代码长度错误")
+		return nil, fmt.Errorf("%w[%s],长度错误", ErrInvalidCode, this.Code)
 	}
 	data := []byte{this.Exchange.Uint8(), 0x0}
 	data = append(data, []byte(this.Code)...) //这里怎么是正序了？
@@ -134,34 +135,29 @@ func (kline) Frame(Type uint8, code string, start, count uint16) (*Frame, error)
 
 func (kline) Decode(bs []byte, c KlineCache) (*KlineResp, error) {
 
-	if len(bs) < 2 {
-		return nil, errors.New("数据长度不足")
-	}
+	r := newReader(bs)
 	resp := &KlineResp{
-		Count: Uint16(bs[:2]),
+		Count: r.Uint16(),
 	}
-	bs = bs[2:]
 
-	var last Price //上条数据(昨天)的收盘价
-	for i := uint16(0); i < resp.Count; i++ {
+	//k线的价格比盘口多一位小数,股票和指数的单位是厘,基金和债券等3位小数的是0.1厘,统一转换成厘
+	decimal := decimalOf(c.Code, c.Decimal) + 1
+	var last Price //上条数据(昨天)的收盘价,服务器的单位
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
 		k := &Kline{
-			Time: GetTime([4]byte(bs[:4]), c.Type),
+			Time: GetTime([4]byte(r.Bytes(4)), c.Type),
 		}
 
-		var open Price
-		bs, open = GetPrice(bs[4:])
-		var _close Price
-		bs, _close = GetPrice(bs)
-		var high Price
-		bs, high = GetPrice(bs)
-		var low Price
-		bs, low = GetPrice(bs)
-
-		k.Last = last
-		k.Open = open + last
-		k.Close = last + open + _close
-		k.High = open + last + high
-		k.Low = open + last + low
+		open := r.Price()
+		_close := r.Price()
+		high := r.Price()
+		low := r.Price()
+
+		k.Last = ScalePrice(last, decimal)
+		k.Open = ScalePrice(open+last, decimal)
+		k.Close = ScalePrice(last+open+_close, decimal)
+		k.High = ScalePrice(open+last+high, decimal)
+		k.Low = ScalePrice(open+last+low, decimal)
 		last = last + open + _close
 
 		/*
@@ -178,33 +174,35 @@ func (kline) Decode(bs []byte, c KlineCache) (*KlineResp, error) {
 			年: 不需要操作
 
 		*/
-		k.Volume = int64(getVolume(Uint32(bs[:4])))
-		bs = bs[4:]
+		k.Volume = int64(r.Volume())
 		switch c.Type {
 		case TypeKlineMinute, TypeKline5Minute, TypeKlineMinute2, TypeKline15Minute, TypeKline30Minute, TypeKline60Minute, TypeKlineDay2:
 			k.Volume /= 100
 		}
-		k.Amount = Price(getVolume(Uint32(bs[:4])) * 1000) //从元转为厘,并去除多余的小数
-		bs = bs[4:]
+		k.Amount = Price(r.Volume() * 1000) //从元转为厘,并去除多余的小数
 
 		switch c.Kind {
 		case KindIndex:
 			//指数和股票的差别,指数多解析4字节,并处理成交量*100
 			k.Volume *= 100
-			k.UpCount = conv.Int([]byte{bs[1], bs[0]})
-			k.DownCount = conv.Int([]byte{bs[3], bs[2]})
-			bs = bs[4:]
+			k.UpCount = int(r.Uint16())
+			k.DownCount = int(r.Uint16())
 		}
 
 		resp.List = append(resp.List, k)
 	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
 	resp.List = FixKlineTime(resp.List)
 	return resp, nil
 }
 
 type KlineCache struct {
-	Type uint8  //1分钟,5分钟,日线等
-	Kind string //指数,个股等
+	Type    uint8  //1分钟,5分钟,日线等
+	Kind    string //指数,个股等
+	Code    string //代码,例sh510300,用于推断价格的小数位
+	Decimal int8   //价格的小数位,为0时根据代码推断,见ScalePrice
 }
 
 // FixKlineTime 修复盘内下午(13~15点)拉取数据的时候,11.30的时间变成13.00
@@ -315,6 +313,75 @@ func (this Klines) Merge(n int) Klines {
 	return ks
 }
 
+// Qfq 前复权,以最新的价格为基准,调整除权除息之前的价格,K线需要按时间正序,日K线和分钟K线都适用
+func (this Klines) Qfq(xs XdXrs) Klines {
+	fs := this.factors(xs)
+	if len(fs) == 0 {
+		return Klines{}
+	}
+	last := fs[len(fs)-1]
+	return this.adjust(fs, func(f float64) float64 { return last / f })
+}
+
+// Hfq 后复权,以上市的价格为基准,调整除权除息之后的价格,K线需要按时间正序,日K线和分钟K线都适用
+func (this Klines) Hfq(xs XdXrs) Klines {
+	fs := this.factors(xs)
+	if len(fs) == 0 {
+		return Klines{}
+	}
+	first := fs[0]
+	return this.adjust(fs, func(f float64) float64 { return first / f })
+}
+
+// factors 计算每根K线的累计复权因子,除权日的第一根K线使用上一根K线的收盘价计算
+func (this Klines) factors(xs XdXrs) []float64 {
+	xs = xs.ExRights()
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Date.Before(xs[j].Date) })
+	fs := make([]float64, len(this))
+	factor := 1.0
+	index := 0
+	for i, k := range this {
+		for index < len(xs) && dateInt(xs[index].Date) <= dateInt(k.Time) {
+			//上市之前的除权信息忽略
+			if i > 0 && dateInt(xs[index].Date) > dateInt(this[i-1].Time) {
+				factor *= xs[index].Factor(this[i-1].Close)
+			}
+			index++
+		}
+		fs[i] = factor
+	}
+	return fs
+}
+
+// adjust 根据复权因子生成新的K线,成交量和成交额不变
+func (this Klines) adjust(fs []float64, ratio func(f float64) float64) Klines {
+	ls := make(Klines, 0, len(this))
+	for i, v := range this {
+		r := ratio(fs[i])
+		k := *v
+		k.Open = adjustPrice(v.Open, r)
+		k.High = adjustPrice(v.High, r)
+		k.Low = adjustPrice(v.Low, r)
+		k.Close = adjustPrice(v.Close, r)
+		k.Last = adjustPrice(v.Last, r)
+		if i > 0 {
+			k.Last = ls[i-1].Close
+		}
+		ls = append(ls, &k)
+	}
+	return ls
+}
+
+func adjustPrice(p Price, r float64) Price {
+	return Price(math.Round(float64(p) * r))
+}
+
+// dateInt 日期转成20060102的数字,方便比较,忽略时区
+func dateInt(t time.Time) int {
+	year, month, day := t.Date()
+	return year*10000 + int(month)*100 + day
+}
+
 //// Kline 计算多个K线,成一个K线
 //func (this Klines) Kline() *Kline {
 //	if this == nil {
@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"errors"
 	"fmt"
 	"time"
 )
@@ -12,13 +11,67 @@ type MinuteResp struct {
 }
 
 type PriceNumber struct {
-	Time   string
-	Price  Price
-	Number int
+	Time     time.Time //时间,每个点是这一分钟结束的时间,例09:31
+	Price    Price     //价格
+	AvgPrice Price     //均价,分时图的黄线
+	Number   int       //成交量(手)
 }
 
 func (this PriceNumber) String() string {
-	return fmt.Sprintf("%s \t%-6s \t%-6d(手)", this.Time, this.Price, this.Number)
+	return fmt.Sprintf("%s \t%-6s \t均价:%-6s \t%-6d(手)", this.Time.Format("15:04"), this.Price, this.AvgPrice, this.Number)
+}
+
+// MinuteCache 分时解析需要的日期和价格小数位,响应数据里没有
+type MinuteCache struct {
+	Date    string //日期,20060102
+	Code    string //代码,用于推断价格的小数位
+	Decimal int8   //价格的小数位,0表示根据代码推断,见PriceDecimal
+}
+
+// minuteTime 第i个分时点的时间,上午09:31-11:30,下午13:01-15:00
+func minuteTime(date time.Time, i int) time.Time {
+	t := date.Add(time.Hour*9 + time.Minute*time.Duration(31+i))
+	if i >= 120 {
+		t = t.Add(time.Minute * 90)
+	}
+	return t
+}
+
+/*
+decodeMinute 解析分时数据,实时分时和历史分时的格式一样,只是头部的长度不同
+每个点: 价格(相对上个点的差值) 均价(相对上个点的差值) 成交量,价格的单位见ScalePrice
+*/
+func decodeMinute(bs []byte, skip int, c MinuteCache) (*MinuteResp, error) {
+	date, err := time.ParseInLocation("20060102", c.Date, LocationCST)
+	if err != nil {
+		return nil, err
+	}
+
+	decimal := decimalOf(c.Code, c.Decimal)
+
+	r := newReader(bs)
+	resp := &MinuteResp{
+		Count: r.Uint16(),
+	}
+	r.Skip(skip)
+
+	price, avg := Price(0), Price(0)
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		price += r.Price()
+		avg += r.Price()
+		number := r.Int()
+		resp.List = append(resp.List, PriceNumber{
+			Time:     minuteTime(date, int(i)),
+			Price:    ScalePrice(price, decimal),
+			AvgPrice: ScalePrice(avg, decimal),
+			Number:   number,
+		})
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 type minute struct{}
@@ -37,34 +90,7 @@ func (this *minute) Frame(code string) (*Frame, error) {
 	}, nil
 }
 
-func (this *minute) Decode(bs []byte) (*MinuteResp, error) {
-
-	if len(bs) < 6 {
-		return nil, errors.New("数据长度不足")
-	}
-
-	resp := &MinuteResp{
-		Count: Uint16(bs[:2]),
-	}
-	//2-6字节是啥?
-	bs = bs[6:]
-	price := Price(0)
-
-	t := time.Date(0, 0, 0, 9, 0, 0, 0, time.Local)
-	for i := uint16(0); i < resp.Count; i++ {
-		bs, price = GetPrice(bs)
-		bs, _ = CutInt(bs) //这个是什么
-		var number int
-		bs, number = CutInt(bs)
-		if i == 120 {
-			t = t.Add(time.Hour * 2)
-		}
-		resp.List = append(resp.List, PriceNumber{
-			Time:   t.Add(time.Minute * time.Duration(i)).Format("15:04"),
-			Price:  price,
-			Number: number,
-		})
-	}
-
-	return resp, nil
+// Decode 数量(2) 未知(2),之前按未知(4)解析导致错位,价格也不是差值,所以结果不对
+func (this *minute) Decode(bs []byte, c MinuteCache) (*MinuteResp, error) {
+	return decodeMinute(bs, 2, c)
 }
@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_minute_Decode(t *testing.T) {
+	//数量2 未知2 + 2个点(价格差值,均价差值,成交量)
+	bs := []byte{0x02, 0x00, 0x00, 0x00}
+	bs = append(bs, 0xb2, 0x12, 0xb2, 0x12, 0x0a) //1202分 1202分 10手
+	bs = append(bs, 0x42, 0x01, 0x14)             //-2分 +1分 20手
+	resp, err := MMinute.Decode(bs, MinuteCache{Date: "20241115"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.List) != 2 {
+		t.Fatalf("预期2个点,得到%d", len(resp.List))
+	}
+	want := []PriceNumber{
+		{Price: 12020, AvgPrice: 12020, Number: 10},
+		{Price: 12000, AvgPrice: 12030, Number: 20},
+	}
+	for i, v := range resp.List {
+		if v.Price != want[i].Price || v.AvgPrice != want[i].AvgPrice || v.Number != want[i].Number {
+			t.Errorf("[%d] 预期%v,得到%v", i, want[i], v)
+		}
+	}
+	if s := resp.List[1].Time.Format("2006-01-02 15:04"); s != "2024-11-15 09:32" {
+		t.Errorf("时间预期2024-11-15 09:32,得到%s", s)
+	}
+}
+
+func Test_minuteTime(t *testing.T) {
+	date := time.Date(2024, 11, 15, 0, 0, 0, 0, LocationCST)
+	for i, want := range map[int]string{0: "09:31", 119: "11:30", 120: "13:01", 239: "15:00"} {
+		if got := minuteTime(date, i).Format("15:04"); got != want {
+			t.Errorf("[%d] 预期%s,得到%s", i, want, got)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package protocol
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type QuotesResp []*Quote
@@ -15,42 +17,71 @@ func (this QuotesResp) String() string {
 	return strings.Join(ls, "\n")
 }
 
+// Quote 盘口(五档行情)
+// ReversedBytes1~8 是协议中还没有确认含义的字段,按原样解析保留,不要依赖它们的值;
+// Estimated开头的字段不是服务器返回的,是客户端根据昨收和成交情况推算的
 type Quote struct {
-	Exchange       Exchange // 市场
-	Code           string   // 股票代码 6个ascii字符串
-	Active1        uint16   // 活跃度
-	K              K        //k线
-	ServerTime     string   // 时间
-	ReversedBytes0 int      // 保留(时间 ServerTime)
-	ReversedBytes1 int      // 保留 这个等于 负的收盘价格?
-	TotalHand      int      // 总手（东财的盘口-总手）
-	Intuition      int      // 现量（东财的盘口-现量）现在成交量
-	Amount         float64  // 金额（东财的盘口-金额）
-	InsideDish     int      // 内盘（东财的盘口-外盘）（和东财对不上）
-	OuterDisc      int      // 外盘（东财的盘口-外盘）（和东财对不上）
-
-	ReversedBytes2 int         // 保留，未知
-	ReversedBytes3 int         // 保留，未知,基金的昨收净值?
+	Exchange       Exchange  // 市场
+	Code           string    // 股票代码 6个ascii字符串
+	Active1        uint16    // 活跃度
+	K              K         //k线
+	ServerTime     time.Time // 行情时间,服务器只返回时分秒,日期取北京时间的当天
+	ReversedBytes1 int       // 保留,含义未知,观察到多数时候等于负的现价,未确认
+	TotalHand      int       // 总手（东财的盘口-总手）
+	Intuition      int       // 现量（东财的盘口-现量）现在成交量
+	Amount         float64   // 金额（东财的盘口-金额）
+	InsideDish     int       // 内盘（东财的盘口-外盘）（和东财对不上）
+	OuterDisc      int       // 外盘（东财的盘口-外盘）（和东财对不上）
+
+	ReversedBytes2 int         // 保留,含义未知
+	ReversedBytes3 int         // 保留,含义未知,基金可能是昨收净值,未确认
 	BuyLevel       PriceLevels // 5档买盘(买1-5)
 	SellLevel      PriceLevels // 5档卖盘(卖1-5)
 
-	ReversedBytes4 uint16  // 保留，未知
-	ReversedBytes5 int     // 保留，未知
-	ReversedBytes6 int     // 保留，未知
-	ReversedBytes7 int     // 保留，未知
-	ReversedBytes8 int     // 保留，未知
-	ReversedBytes9 uint16  // 保留，未知
-	Rate           float64 // 涨速，好像都是0
+	ReversedBytes4 uint16  // 保留,含义未知
+	ReversedBytes5 int     // 保留,含义未知
+	ReversedBytes6 int     // 保留,含义未知
+	ReversedBytes7 int     // 保留,含义未知
+	ReversedBytes8 int     // 保留,含义未知
+	RiseSpeed      float64 // 涨速,单位%,最近几分钟的涨跌幅,有符号
 	Active2        uint16  // 活跃度
+
+	EstimatedLimitUp   Price // 推算的涨停价,仅股票,按LimitPrice的涨跌幅表由昨收计算,新股上市初期等特殊情况不准,ST股票需要客户端设置CodeResolver才能按5%计算
+	EstimatedLimitDown Price // 推算的跌停价,同EstimatedLimitUp
+	EstimatedSuspended bool  // 推算的是否停牌,开盘后没有开盘价且没有成交
+
+	serverTime int // 服务器返回的原始行情时间,见ServerTimeString
+}
+
+// ServerTimeString 服务器返回的原始行情时间,例14595863,
+// Deprecated: 原来的ServerTime字段,使用ServerTime
+func (this *Quote) ServerTimeString() string {
+	return strconv.Itoa(this.serverTime)
+}
+
+// Rate 涨速,
+// Deprecated: 原来的Rate字段按无符号解析,下跌时数值错误,使用RiseSpeed
+func (this *Quote) Rate() float64 {
+	return this.RiseSpeed
+}
+
+// IsLimitUp 是否涨停,按推算的涨停价判断
+func (this *Quote) IsLimitUp() bool {
+	return this.EstimatedLimitUp > 0 && this.K.Close >= this.EstimatedLimitUp
+}
+
+// IsLimitDown 是否跌停,按推算的跌停价判断
+func (this *Quote) IsLimitDown() bool {
+	return this.EstimatedLimitDown > 0 && this.K.Close > 0 && this.K.Close <= this.EstimatedLimitDown
 }
 
 func (this *Quote) String() string {
-	return fmt.Sprintf(`%s%s
+	return fmt.Sprintf(`%s%s %s 涨速：%.2f%%
 %s
 总手：%s, 现量：%s, 总金额：%s, 内盘：%s, 外盘：%s
 %s%s
 `,
-		this.Exchange.String(), this.Code, this.K,
+		this.Exchange.String(), this.Code, this.ServerTime.Format("15:04:05"), this.RiseSpeed, this.K,
 		IntUnitString(this.TotalHand), IntUnitString(this.Intuition),
 		FloatUnitString(this.Amount), IntUnitString(this.InsideDish), IntUnitString(this.OuterDisc),
 		this.SellLevel.String(), this.BuyLevel.String(),
@@ -106,67 +137,120 @@ b212 昨天收盘价1186
 8defd10c 服务时间
 c005bed2668e05be15804d8ba12cb3b13a0083c3034100badc029d014201bc990384f70443029da503b7af074403a6e501b9db044504a6e2028dd5048d050000000000005909
 */
-func (this quote) Decode(bs []byte) QuotesResp {
-
-	//logs.Debug(hex.EncodeToString(bs))
+func (this quote) Decode(bs []byte, c QuoteCache) (QuotesResp, error) {
 
+	r := newReader(bs)
 	resp := QuotesResp{}
 
-	//前2字节是什么?
-	bs = bs[2:]
+	r.Skip(2)
+	number := r.Uint16()
 
-	number := Uint16(bs[:2])
-	bs = bs[2:]
-
-	for i := uint16(0); i < number; i++ {
+	for i := uint16(0); i < number && r.Err() == nil; i++ {
 		sec := &Quote{
-			Exchange: Exchange(bs[0]),
-			Code:     string(UTF8ToGBK(bs[1:7])),
-			Active1:  Uint16(bs[7:9]),
+			Exchange: Exchange(r.Uint8()),
+			Code:     string(UTF8ToGBK(r.Bytes(6))),
+			Active1:  r.Uint16(),
 		}
-		bs, sec.K = DecodeK(bs[9:])
-		bs, sec.ReversedBytes0 = CutInt(bs)
-		sec.ServerTime = fmt.Sprintf("%d", sec.ReversedBytes0)
-		bs, sec.ReversedBytes1 = CutInt(bs)
-		bs, sec.TotalHand = CutInt(bs)
-		bs, sec.Intuition = CutInt(bs)
-		sec.Amount = getVolume(Uint32(bs[:4]))
-		bs, sec.InsideDish = CutInt(bs[4:])
-		bs, sec.OuterDisc = CutInt(bs)
-		bs, sec.ReversedBytes2 = CutInt(bs)
-		bs, sec.ReversedBytes3 = CutInt(bs)
-
-		var p Price
+		code := sec.Exchange.String() + sec.Code
+		decimal := decimalOf(code, c.Decimals[code])
+		sec.K = r.K(decimal)
+		sec.serverTime = r.Int()
+		sec.ServerTime = quoteTime(sec.serverTime, time.Now().In(LocationCST))
+		sec.ReversedBytes1 = r.Int()
+		sec.TotalHand = r.Int()
+		sec.Intuition = r.Int()
+		sec.Amount = r.Volume()
+		sec.InsideDish = r.Int()
+		sec.OuterDisc = r.Int()
+		sec.ReversedBytes2 = r.Int()
+		sec.ReversedBytes3 = r.Int()
+
 		for i := 0; i < 5; i++ {
 			buyLevel := PriceLevel{Buy: true}
 			sellLevel := PriceLevel{}
 
-			bs, p = GetPrice(bs)
-			buyLevel.Price = p*10 + sec.K.Close
-			bs, p = GetPrice(bs)
-			sellLevel.Price = p*10 + sec.K.Close
+			buyLevel.Price = ScalePrice(r.Price(), decimal) + sec.K.Close
+			sellLevel.Price = ScalePrice(r.Price(), decimal) + sec.K.Close
 
-			bs, buyLevel.Number = CutInt(bs)
-			bs, sellLevel.Number = CutInt(bs)
+			buyLevel.Number = r.Int()
+			sellLevel.Number = r.Int()
 
 			sec.BuyLevel[i] = buyLevel
 			sec.SellLevel[i] = sellLevel
 		}
 
-		sec.ReversedBytes4 = Uint16(bs[:2])
-		bs, sec.ReversedBytes5 = CutInt(bs[2:])
-		bs, sec.ReversedBytes6 = CutInt(bs)
-		bs, sec.ReversedBytes7 = CutInt(bs)
-		bs, sec.ReversedBytes8 = CutInt(bs)
-		sec.ReversedBytes9 = Uint16(bs[:2])
-
-		sec.Rate = float64(sec.ReversedBytes9) / 100
-		sec.Active2 = Uint16(bs[2:4])
+		sec.ReversedBytes4 = r.Uint16()
+		sec.ReversedBytes5 = r.Int()
+		sec.ReversedBytes6 = r.Int()
+		sec.ReversedBytes7 = r.Int()
+		sec.ReversedBytes8 = r.Int()
+		sec.RiseSpeed = float64(int16(r.Uint16())) / 100
+		sec.Active2 = r.Uint16()
 
-		bs = bs[4:]
+		sec.EstimatedLimitUp, sec.EstimatedLimitDown = LimitPrice(code, sec.K.Last, false)
+		sec.EstimatedSuspended = sec.K.Last > 0 && sec.K.Open == 0 && sec.TotalHand == 0 &&
+			!sec.ServerTime.IsZero() && sec.ServerTime.Hour()*60+sec.ServerTime.Minute() >= 9*60+25
 
 		resp = append(resp, sec)
 	}
 
-	return resp
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+/*
+quoteTime 解析盘口的时间,格式是HHMMxxxx,例14595863
+当MM<60时,xxxx是分钟内的进度,乘以60/10000得到秒,即14:59:35.178
+当MM>=60时,后6位是小时内的进度,乘以3600/1000000得到分秒
+为0时表示没有时间,返回零值
+*/
+func quoteTime(n int, today time.Time) time.Time {
+	if n <= 0 {
+		return time.Time{}
+	}
+	hour := n / 1000000
+	var offset time.Duration
+	if m := n / 10000 % 100; m < 60 {
+		offset = time.Duration(m)*time.Minute + time.Duration(n%10000)*time.Minute/10000
+	} else {
+		offset = time.Duration(n%1000000) * time.Hour / 1000000
+	}
+	y, M, d := today.Date()
+	return time.Date(y, M, d, hour, 0, 0, 0, LocationCST).Add(offset).Truncate(time.Millisecond)
+}
+
+// LimitPrice 根据昨收计算股票的涨停价和跌停价,非股票返回0
+// 科创板和创业板20%,北交所30%,其他10%,ST(主板)5%,价格按分四舍五入
+func LimitPrice(code string, last Price, st bool) (up, down Price) {
+	if !IsStock(code) || last <= 0 {
+		return 0, 0
+	}
+	code = strings.ToLower(code)
+	rate := Price(10)
+	switch {
+	case IsBJStock(code):
+		rate = 30
+	case code[:5] == "sh688" || code[:5] == "sz300" || code[:5] == "sz301":
+		rate = 20
+	case st:
+		rate = 5
+	}
+	cent := last / 10
+	up = (cent*(100+rate) + 50) / 100 * 10
+	down = (cent*(100-rate) + 50) / 100 * 10
+	return
+}
+
+// IsST 根据名称判断是否是ST股票,名称以ST或*ST开头,例 ST平安,*ST平安,
+// 不能用包含判断,部分正常股票的名称(英文或拼音)也含有ST
+func IsST(name string) bool {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	return strings.HasPrefix(strings.TrimPrefix(name, "*"), "ST")
+}
+
+// QuoteCache 盘口解析需要的价格小数位
+type QuoteCache struct {
+	Decimals map[string]int8 //代码(例sz159558)对应的价格小数位,没有的根据代码推断,见PriceDecimal
 }
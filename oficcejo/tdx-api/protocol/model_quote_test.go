@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"testing"
+	"time"
 )
 
 func Test_quote_Frame(t *testing.T) {
@@ -13,3 +14,59 @@ func Test_quote_Frame(t *testing.T) {
 	}
 	t.Log(f.Bytes().HEX())
 }
+
+func Test_quoteTime(t *testing.T) {
+	today := time.Date(2024, 11, 15, 0, 0, 0, 0, LocationCST)
+	cases := map[int]string{
+		14595863: "14:59:35.178",
+		9300000:  "09:30:00.000",
+		10750000: "10:45:00.000", //分钟位>=60,后6位是小时内的进度
+	}
+	for n, want := range cases {
+		if got := quoteTime(n, today).Format("15:04:05.000"); got != want {
+			t.Errorf("%d: 预期%s,得到%s", n, want, got)
+		}
+	}
+	if !quoteTime(0, today).IsZero() {
+		t.Error("预期零值")
+	}
+}
+
+func TestLimitPrice(t *testing.T) {
+	cases := []struct {
+		code     string
+		last     Price
+		st       bool
+		up, down Price
+	}{
+		{"sz000001", 11500, false, 12650, 10350},
+		{"sh600000", 10230, false, 11250, 9210},
+		{"sz000001", 11500, true, 12080, 10930},
+		{"sh688001", 11500, true, 13800, 9200},
+		{"sz300750", 11500, false, 13800, 9200},
+		{"bj920001", 11500, false, 14950, 8050},
+		{"sh000001", 3330500, false, 0, 0},
+	}
+	for _, v := range cases {
+		up, down := LimitPrice(v.code, v.last, v.st)
+		if up != v.up || down != v.down {
+			t.Errorf("%s(%d): 预期%d/%d,得到%d/%d", v.code, v.last, v.up, v.down, up, down)
+		}
+	}
+}
+
+func TestIsST(t *testing.T) {
+	cases := map[string]bool{
+		"ST平安":   true,
+		"*ST平安":  true,
+		"st平安":   true,
+		"平安银行":   false,
+		"BOSTON": false,
+		"平安ST":   false,
+	}
+	for name, want := range cases {
+		if got := IsST(name); got != want {
+			t.Errorf("%s: 预期%v,得到%v", name, want, got)
+		}
+	}
+}
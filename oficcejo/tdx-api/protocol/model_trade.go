@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
@@ -10,8 +9,8 @@ import (
 )
 
 var (
-	// 中国标准时间时区 (UTC+8)
-	locationCST = time.FixedZone("CST", 8*3600)
+	// LocationCST 中国标准时间时区 (UTC+8),行情的日期和时间都是北京时间,不要使用本地时区
+	LocationCST = time.FixedZone("CST", 8*3600)
 )
 
 type TradeResp struct {
@@ -89,41 +88,37 @@ func (trade) Frame(code string, start, count uint16) (*Frame, error) {
 
 func (trade) Decode(bs []byte, c TradeCache) (*TradeResp, error) {
 
-	_, code, err := DecodeCode(c.Code)
-	if err != nil {
+	if _, _, err := DecodeCode(c.Code); err != nil {
 		return nil, err
 	}
+	decimal := decimalOf(c.Code, c.Decimal)
 
-	if len(bs) < 2 {
-		return nil, errors.New("数据长度不足")
-	}
-
+	r := newReader(bs)
 	resp := &TradeResp{
-		Count: Uint16(bs[:2]),
+		Count: r.Uint16(),
 	}
 
-	bs = bs[2:]
-
 	lastPrice := Price(0)
-	for i := uint16(0); i < resp.Count; i++ {
-		timeStr := GetHourMinute([2]byte(bs[:2]))
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		timeStr := GetHourMinute([2]byte(r.Bytes(2)))
 		// 数据中的时间本身就是北京时间，使用CST时区解析
-		t, err := time.ParseInLocation("2006010215:04", c.Date+timeStr, locationCST)
+		t, err := time.ParseInLocation("2006010215:04", c.Date+timeStr, LocationCST)
 		if err != nil {
 			return nil, err
 		}
 		mt := &Trade{Time: t}
-		var sub Price
-		bs, sub = GetPrice(bs[2:])
-		lastPrice += sub * 10 //把分转换成厘
-		mt.Price = lastPrice / basePrice(code)
-		bs, mt.Volume = CutInt(bs)
-		bs, mt.Number = CutInt(bs)
-		bs, mt.Status = CutInt(bs)
-		bs, _ = CutInt(bs) //这个得到的是0，不知道是啥
+		lastPrice += r.Price()
+		mt.Price = ScalePrice(lastPrice, decimal)
+		mt.Volume = r.Int()
+		mt.Number = r.Int()
+		mt.Status = r.Int()
+		r.Int() //这个得到的是0，不知道是啥
 		resp.List = append(resp.List, mt)
 	}
 
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -234,6 +229,7 @@ func (this Trades) klinesForDay(date time.Time) Klines {
 }
 
 type TradeCache struct {
-	Date string //日期
-	Code string //计算倍数
+	Date    string //日期
+	Code    string //代码,用于推断价格的小数位
+	Decimal int8   //价格的小数位,0表示根据代码推断,见PriceDecimal
 }
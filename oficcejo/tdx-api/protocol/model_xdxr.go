@@ -0,0 +1,195 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type XdXrResp struct {
+	Count uint16
+	List  XdXrs
+}
+
+// XdXr 除权除息信息,包括分红送配和股本变动
+type XdXr struct {
+	Date     time.Time //日期
+	Category uint8     //类型,1是除权除息,其他见CategoryName
+
+	Cash         float64 //分红,每10股派现金(元)
+	RightsPrice  float64 //配股价(元)
+	BonusShares  float64 //送转股,每10股送转股数
+	RightsShares float64 //配股,每10股配股数
+
+	ShrinkRatio   float64 //扩缩股比例,类型11,12有效
+	ExercisePrice float64 //行权价,类型13,14有效
+	WarrantShares float64 //权证份数,类型13,14有效
+
+	FloatBefore float64 //变动前流通股本(万股)
+	FloatAfter  float64 //变动后流通股本(万股)
+	TotalBefore float64 //变动前总股本(万股)
+	TotalAfter  float64 //变动后总股本(万股)
+}
+
+func (this *XdXr) String() string {
+	switch this.Category {
+	case 1:
+		return fmt.Sprintf("%s %s 每10股派%.2f元 送转%.2f股 配%.2f股 配股价%.2f元",
+			this.Date.Format("2006-01-02"), this.CategoryName(), this.Cash, this.BonusShares, this.RightsShares, this.RightsPrice)
+	default:
+		return fmt.Sprintf("%s %s 流通股本:%.2f->%.2f(万股) 总股本:%.2f->%.2f(万股)",
+			this.Date.Format("2006-01-02"), this.CategoryName(), this.FloatBefore, this.FloatAfter, this.TotalBefore, this.TotalAfter)
+	}
+}
+
+// CategoryName 类型名称
+func (this *XdXr) CategoryName() string {
+	switch this.Category {
+	case 1:
+		return "除权除息"
+	case 2:
+		return "送配股上市"
+	case 3:
+		return "非流通股上市"
+	case 4:
+		return "未知股本变动"
+	case 5:
+		return "股本变化"
+	case 6:
+		return "增发新股"
+	case 7:
+		return "股份回购"
+	case 8:
+		return "增发新股上市"
+	case 9:
+		return "转配股上市"
+	case 10:
+		return "可转债上市"
+	case 11:
+		return "扩缩股"
+	case 12:
+		return "非流通股缩股"
+	case 13:
+		return "送认购权证"
+	case 14:
+		return "送认沽权证"
+	default:
+		return "未知"
+	}
+}
+
+// IsExRights 是否是除权除息,只有除权除息会影响复权价格
+func (this *XdXr) IsExRights() bool {
+	return this.Category == 1
+}
+
+// Factor 通过除权前一天的收盘价计算复权因子,除权参考价/前收盘价
+func (this *XdXr) Factor(last Price) float64 {
+	if !this.IsExRights() || last <= 0 {
+		return 1
+	}
+	lastClose := last.Float64()
+	//除权参考价=(前收盘价*10-派息+配股价*配股数)/(10+送转股数+配股数)
+	refer := (lastClose*10 - this.Cash + this.RightsPrice*this.RightsShares) / (10 + this.BonusShares + this.RightsShares)
+	if refer <= 0 {
+		return 1
+	}
+	return refer / lastClose
+}
+
+type XdXrs []*XdXr
+
+// ExRights 筛选出除权除息的数据
+func (this XdXrs) ExRights() XdXrs {
+	ls := XdXrs(nil)
+	for _, v := range this {
+		if v.IsExRights() {
+			ls = append(ls, v)
+		}
+	}
+	return ls
+}
+
+type xdxr struct{}
+
+// Frame 0c1f187600010b000b000f000100 00 303030303031
+func (xdxr) Frame(code string) (*Frame, error) {
+	exchange, number, err := DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte{0x01, 0x00} //数量,固定1个
+	data = append(data, exchange.Uint8())
+	data = append(data, []byte(number)...)
+	return &Frame{
+		Control: Control01,
+		Type:    TypeXdXr,
+		Data:    data,
+	}, nil
+}
+
+/*
+Decode
+前9字节是市场和代码,后2字节是数量,每条数据29字节
+
+00 交易所
+303030303031 代码
+00 未知
+a7d83201 日期
+01 类型
+00000000 00000000 00000000 00000000 数据域,根据类型不同解析不同
+*/
+func (xdxr) Decode(bs []byte) (*XdXrResp, error) {
+	r := newReader(bs)
+	r.Skip(9)
+	resp := &XdXrResp{
+		Count: r.Uint16(),
+	}
+
+	for i := uint16(0); i < resp.Count && r.Err() == nil; i++ {
+		r.Skip(8)
+		x := &XdXr{
+			Date:     GetTime([4]byte(r.Bytes(4)), TypeKlineDay),
+			Category: r.Uint8(),
+		}
+		data := r.Bytes(16)
+		switch x.Category {
+		case 1:
+			x.Cash = getFloat32(data[0:4])
+			x.RightsPrice = getFloat32(data[4:8])
+			x.BonusShares = getFloat32(data[8:12])
+			x.RightsShares = getFloat32(data[12:16])
+		case 11, 12:
+			x.ShrinkRatio = getFloat32(data[8:12])
+		case 13, 14:
+			x.ExercisePrice = getFloat32(data[0:4])
+			x.WarrantShares = getFloat32(data[8:12])
+		default:
+			x.FloatBefore = getShareCapital(Uint32(data[0:4]))
+			x.TotalBefore = getShareCapital(Uint32(data[4:8]))
+			x.FloatAfter = getShareCapital(Uint32(data[8:12]))
+			x.TotalAfter = getShareCapital(Uint32(data[12:16]))
+		}
+		resp.List = append(resp.List, x)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// getFloat32 小端字节转float32
+func getFloat32(bs []byte) float64 {
+	f := float64(math.Float32frombits(Uint32(bs)))
+	//去除float32转换带来的精度误差
+	return math.Round(f*1e4) / 1e4
+}
+
+// getShareCapital 股本,和成交量是同样的编码方式
+func getShareCapital(val uint32) float64 {
+	if val == 0 {
+		return 0
+	}
+	return getVolume(val)
+}
@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func Test_xdxr_Frame(t *testing.T) {
+	//预期0c00000000010b000b000f00010000303030303031
+	f, err := MXdXr.Frame("sz000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(f.Bytes().HEX())
+	if f.Bytes().HEX() != "0c00000000010b000b000f00010000303030303031" {
+		t.Error("编码错误")
+	}
+}
+
+func Test_xdxr_Decode(t *testing.T) {
+	s := "00303030303031000001000030303030303100e6d83401017b14e640000000000000000000000000"
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err := MXdXr.Decode(bs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(resp.List) != 1 {
+		t.Errorf("预期1条,得到%d条", len(resp.List))
+		return
+	}
+	x := resp.List[0]
+	t.Log(x)
+	if x.Date.Format("20060102") != "20240614" || x.Category != 1 || x.Cash != 7.19 {
+		t.Error("解析错误")
+	}
+}
+
+func TestKlines_Qfq(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 15, 0, 0, 0, time.Local) }
+	ks := Klines{
+		{Time: day(12), Open: 10000, High: 10000, Low: 10000, Close: 10000},
+		{Time: day(13), Open: 10000, High: 10000, Low: 10000, Close: 10000},
+		{Time: day(14), Open: 9000, High: 9000, Low: 9000, Close: 9000},
+	}
+	//每10股派10元,除权参考价9元
+	xs := XdXrs{{Date: day(14), Category: 1, Cash: 10}}
+
+	qfq := ks.Qfq(xs)
+	for _, v := range qfq {
+		t.Log(v)
+	}
+	if qfq[0].Close != 9000 || qfq[1].Close != 9000 || qfq[2].Close != 9000 || qfq[2].Last != 9000 {
+		t.Error("前复权计算错误")
+	}
+
+	hfq := ks.Hfq(xs)
+	for _, v := range hfq {
+		t.Log(v)
+	}
+	if hfq[0].Close != 10000 || hfq[2].Close != 10000 {
+		t.Error("后复权计算错误")
+	}
+
+	if ks[0].Close != 10000 {
+		t.Error("原数据被修改")
+	}
+}
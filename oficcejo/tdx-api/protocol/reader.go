@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+)
+
+// reader 带边界检查的游标,用于解析响应数据
+// 数据不足时记录第一个错误,之后的读取都返回零值(不会panic),解析完成后通过Err判断
+type reader struct {
+	bs  []byte
+	pos int
+	err error
+}
+
+func newReader(bs []byte) *reader {
+	return &reader{bs: bs}
+}
+
+// Err 第一个读取错误
+func (this *reader) Err() error {
+	return this.err
+}
+
+// Len 剩余未读取的字节数
+func (this *reader) Len() int {
+	return len(this.bs) - this.pos
+}
+
+func (this *reader) need(n int) bool {
+	if this.err != nil {
+		return false
+	}
+	if n < 0 || this.Len() < n {
+		this.err = fmt.Errorf("数据长度不足,位置%d需要%d字节,剩余%d字节", this.pos, n, this.Len())
+		return false
+	}
+	return true
+}
+
+// Bytes 读取n字节,数据不足时返回n个0,方便转换成定长数组
+func (this *reader) Bytes(n int) []byte {
+	if !this.need(n) {
+		if n < 0 {
+			n = 0
+		}
+		return make([]byte, n)
+	}
+	bs := this.bs[this.pos : this.pos+n]
+	this.pos += n
+	return bs
+}
+
+// Rest 读取剩余的全部字节
+func (this *reader) Rest() []byte {
+	return this.Bytes(this.Len())
+}
+
+// Skip 跳过n字节
+func (this *reader) Skip(n int) {
+	if this.need(n) {
+		this.pos += n
+	}
+}
+
+func (this *reader) Uint8() uint8 {
+	return this.Bytes(1)[0]
+}
+
+// Uint16 小端
+func (this *reader) Uint16() uint16 {
+	return Uint16(this.Bytes(2))
+}
+
+// Uint32 小端
+func (this *reader) Uint32() uint32 {
+	return Uint32(this.Bytes(4))
+}
+
+// Float32 小端的float32
+func (this *reader) Float32() float64 {
+	return float64(math.Float32frombits(this.Uint32()))
+}
+
+// Volume 成交量,成交额等的编码方式,见getVolume
+func (this *reader) Volume() float64 {
+	return getVolume(this.Uint32())
+}
+
+// GBK 读取n字节的GBK字符串,截取到第一个0x00,并转成UTF8
+func (this *reader) GBK(n int) string {
+	return string(UTF8ToGBK(cutZero(this.Bytes(n))))
+}
+
+// varint 变长数据的长度,最后一个字节的最高位是0
+func (this *reader) varint() []byte {
+	if this.err != nil {
+		return nil
+	}
+	for i := this.pos; i < len(this.bs); i++ {
+		if this.bs[i]&0x80 == 0 {
+			bs := this.bs[this.pos : i+1]
+			this.pos = i + 1
+			return bs
+		}
+	}
+	this.err = fmt.Errorf("数据长度不足,位置%d的变长数据不完整", this.pos)
+	return nil
+}
+
+// Price 变长的价格,见GetPrice
+func (this *reader) Price() Price {
+	return getPrice(this.varint())
+}
+
+// Int 变长的整数,见CutInt
+func (this *reader) Int() int {
+	return getData(this.varint())
+}
+
+// K 盘口的价格,昨收,开盘,最高,最低都是相对收盘价的差值,见DecodeK,decimal是价格的小数位,见ScalePrice
+func (this *reader) K(decimal int8) K {
+	k := K{}
+	k.Close = this.Price()
+	k.Last = this.Price() + k.Close
+	k.Open = this.Price() + k.Close
+	k.High = this.Price() + k.Close
+	k.Low = this.Price() + k.Close
+
+	k.Last = ScalePrice(k.Last, decimal)
+	k.Open = ScalePrice(k.Open, decimal)
+	k.Close = ScalePrice(k.Close, decimal)
+	k.High = ScalePrice(k.High, decimal)
+	k.Low = ScalePrice(k.Low, decimal)
+	return k
+}
+
+// Float32Round 小端的float32,并去除精度误差,见getFloat32
+func (this *reader) Float32Round() float64 {
+	return getFloat32(this.Bytes(4))
+}
@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	r := newReader([]byte{0x01, 0x02, 0x03, 0x85, 0x01})
+	if n := r.Uint16(); n != 0x0201 {
+		t.Errorf("预期0x0201,得到0x%04x", n)
+	}
+	r.Skip(1)
+	if p := r.Price(); p != 69 {
+		t.Errorf("预期69,得到%d", p)
+	}
+	if r.Err() != nil {
+		t.Error(r.Err())
+	}
+
+	//数据不足后一直返回零值,并保留第一个错误
+	if n := r.Uint32(); n != 0 || r.Err() == nil {
+		t.Errorf("预期数据长度不足,得到%d,%v", n, r.Err())
+	}
+	err := r.Err()
+	if _ = r.Int(); r.Err() != err {
+		t.Errorf("预期保留第一个错误")
+	}
+	t.Log(err)
+
+	//变长数据没有结束标识
+	r = newReader([]byte{0x80, 0x80})
+	if r.Price(); r.Err() == nil {
+		t.Error("预期变长数据不完整")
+	}
+}
+
+func TestDecode_Truncated(t *testing.T) {
+	kline, _ := hex.DecodeString("0a0078da340198b8018404bc055ee8b3e949ad2b094f79da34010af801a002cc0260dec949859ded4e7ada34016882028e04e603b8f91e4a111f394f7dda3401e401c20200f604f84d2b4ad4d0444f7eda3401721eaa0268d87bc549ee80e34e7fda34011e288601c601d08db849230ed54e80da3401727c32da013023584999a0784e81da3401147c0ad001d0fa86498d989a4e84da34015e6800d60278c28e491ca6a14e85da340154d001b801da01403e924989d6a54e")
+	for i := 0; i < len(kline); i++ {
+		if _, err := MKline.Decode(kline[:i], KlineCache{Type: TypeKlineDay}); err == nil {
+			t.Errorf("截取%d字节,预期错误", i)
+		}
+	}
+	if _, err := MKline.Decode(kline, KlineCache{Type: TypeKlineDay}); err != nil {
+		t.Error(err)
+	}
+
+	trade := TradeCache{Date: "20241115", Code: "sz000001"}
+	minute := MinuteCache{Date: "20241115"}
+	decoders := map[string]func(bs []byte) (any, error){
+		"connect":         func(bs []byte) (any, error) { return MConnect.Decode(bs) },
+		"count":           func(bs []byte) (any, error) { return MCount.Decode(bs) },
+		"code":            func(bs []byte) (any, error) { return MCode.Decode(bs) },
+		"quote":           func(bs []byte) (any, error) { return MQuote.Decode(bs, QuoteCache{}) },
+		"minute":          func(bs []byte) (any, error) { return MMinute.Decode(bs, minute) },
+		"historyMinute":   func(bs []byte) (any, error) { return MHistoryMinute.Decode(bs, minute) },
+		"trade":           func(bs []byte) (any, error) { return MTrade.Decode(bs, trade) },
+		"historyTrade":    func(bs []byte) (any, error) { return MHistoryTrade.Decode(bs, trade) },
+		"kline":           func(bs []byte) (any, error) { return MKline.Decode(bs, KlineCache{Kind: KindIndex}) },
+		"xdxr":            func(bs []byte) (any, error) { return MXdXr.Decode(bs) },
+		"finance":         func(bs []byte) (any, error) { return MFinance.Decode(bs) },
+		"companyCategory": func(bs []byte) (any, error) { return MCompanyCategory.Decode(bs) },
+		"companyContent":  func(bs []byte) (any, error) { return MCompanyContent.Decode(bs) },
+		"blockMeta":       func(bs []byte) (any, error) { return MBlockMeta.Decode(bs) },
+		"block":           func(bs []byte) (any, error) { return MBlock.Decode(bs) },
+		"auction":         func(bs []byte) (any, error) { return MAuction.Decode(bs, AuctionCache{Date: "20241115"}) },
+		"exCount":         func(bs []byte) (any, error) { return MExCount.Decode(bs) },
+		"exMarket":        func(bs []byte) (any, error) { return MExMarket.Decode(bs) },
+		"exInstrument":    func(bs []byte) (any, error) { return MExInstrument.Decode(bs) },
+		"exQuote":         func(bs []byte) (any, error) { return MExQuote.Decode(bs) },
+		"exKline":         func(bs []byte) (any, error) { return MExKline.Decode(bs, KlineCache{}) },
+		"exTrade":         func(bs []byte) (any, error) { return MExTrade.Decode(bs, trade) },
+	}
+	inputs := [][]byte{
+		nil,
+		{0x01},
+		bytes.Repeat([]byte{0xff}, 20),
+		bytes.Repeat([]byte{0x80}, 64),
+	}
+	for name, f := range decoders {
+		for _, in := range inputs {
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Errorf("%s解析%x时panic: %v", name, in, e)
+					}
+				}()
+				//定长的响应可能正好能解析,这里只要求不panic,过短的数据必须返回错误
+				if _, err := f(in); err == nil && len(in) < 2 {
+					t.Errorf("%s解析%x,预期错误", name, in)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"strings"
+)
+
+// SecurityType 证券类型,比AssetClass更细,例如区分ETF和LOF,可转债和回购
+type SecurityType string
+
+const (
+	SecurityStock  SecurityType = "stock"  //A股股票
+	SecurityIndex  SecurityType = "index"  //指数
+	SecurityETF    SecurityType = "etf"    //ETF
+	SecurityLOF    SecurityType = "lof"    //LOF,封闭式基金等其他场内基金
+	SecurityREIT   SecurityType = "reit"   //公募REITs
+	SecurityCBond  SecurityType = "cbond"  //可转债
+	SecurityBond   SecurityType = "bond"   //国债,地方债,企业债等
+	SecurityRepo   SecurityType = "repo"   //债券回购
+	SecurityBShare SecurityType = "bshare" //B股
+	SecurityOther  SecurityType = "other"  //其他,例如权证,期权等
+)
+
+// SecurityTypes 全部的证券类型
+var SecurityTypes = []SecurityType{
+	SecurityStock, SecurityIndex, SecurityETF, SecurityLOF, SecurityREIT,
+	SecurityCBond, SecurityBond, SecurityRepo, SecurityBShare, SecurityOther,
+}
+
+// Name 中文名称
+func (this SecurityType) Name() string {
+	switch this {
+	case SecurityStock:
+		return "股票"
+	case SecurityIndex:
+		return "指数"
+	case SecurityETF:
+		return "ETF"
+	case SecurityLOF:
+		return "LOF"
+	case SecurityREIT:
+		return "REITs"
+	case SecurityCBond:
+		return "可转债"
+	case SecurityBond:
+		return "债券"
+	case SecurityRepo:
+		return "回购"
+	case SecurityBShare:
+		return "B股"
+	default:
+		return "其他"
+	}
+}
+
+// Class 对应的资产类别
+func (this SecurityType) Class() AssetClass {
+	switch this {
+	case SecurityStock:
+		return AssetStock
+	case SecurityIndex:
+		return AssetIndex
+	case SecurityETF, SecurityLOF:
+		return AssetETF
+	case SecurityREIT:
+		return AssetREIT
+	case SecurityCBond, SecurityBond, SecurityRepo:
+		return AssetBond
+	case SecurityBShare:
+		return AssetBShare
+	default:
+		return AssetUnknown
+	}
+}
+
+// ParseSecurityType 解析证券类型,不区分大小写,未知的返回false
+func ParseSecurityType(s string) (SecurityType, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, v := range SecurityTypes {
+		if string(v) == s {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+/*
+SecurityTypeOf 根据号段判断证券类型,code需要带交易所前缀,例sz000001
+上海: 000,880,999指数 60,68股票 900B股 51,52,56,58ETF 508REITs 50其他基金 110,111,113,118可转债 204回购 0,1开头的其他债券
+深圳: 00,30股票 399指数 200B股 159ETF 16LOF 180REITs 18其他基金 123,127,128可转债 131回购 10-13其他债券
+北京: 899指数 43,8,92股票
+*/
+func SecurityTypeOf(code string) SecurityType {
+	if len(code) != 8 {
+		return SecurityOther
+	}
+	code = strings.ToLower(code)
+	exchange, number := code[:2], code[2:]
+	has := func(prefix ...string) bool {
+		for _, v := range prefix {
+			if strings.HasPrefix(number, v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch exchange {
+	case ExchangeSH.String():
+		switch {
+		case has("000", "880", "999"):
+			return SecurityIndex
+		case has("60", "68"):
+			return SecurityStock
+		case has("900"):
+			return SecurityBShare
+		case has("51", "52", "56", "58"):
+			return SecurityETF
+		case has("508"):
+			return SecurityREIT
+		case has("50"):
+			return SecurityLOF
+		case has("110", "111", "113", "118"):
+			return SecurityCBond
+		case has("204"):
+			return SecurityRepo
+		case has("0", "1"):
+			return SecurityBond
+		}
+
+	case ExchangeSZ.String():
+		switch {
+		case has("00", "30"):
+			return SecurityStock
+		case has("399"):
+			return SecurityIndex
+		case has("20"):
+			return SecurityBShare
+		case has("159"):
+			return SecurityETF
+		case has("16"):
+			return SecurityLOF
+		case has("180"):
+			return SecurityREIT
+		case has("18"):
+			return SecurityLOF
+		case has("123", "127", "128"):
+			return SecurityCBond
+		case has("131"):
+			return SecurityRepo
+		case has("10", "11", "12", "13"):
+			return SecurityBond
+		}
+
+	case ExchangeBJ.String():
+		switch {
+		case has("899"):
+			return SecurityIndex
+		case has("43", "8", "92"):
+			return SecurityStock
+		}
+	}
+	return SecurityOther
+}
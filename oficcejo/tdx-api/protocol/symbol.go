@@ -0,0 +1,183 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AssetClass 资产类别,SecurityType的粗分类,通过SecurityType.Class得到
+type AssetClass uint8
+
+const (
+	AssetUnknown AssetClass = iota //未知
+	AssetStock                     //A股股票
+	AssetIndex                     //指数
+	AssetETF                       //基金,ETF,LOF等
+	AssetBond                      //债券,可转债,回购等
+	AssetBShare                    //B股
+	AssetREIT                      //公募REITs
+)
+
+func (this AssetClass) String() string {
+	switch this {
+	case AssetStock:
+		return "stock"
+	case AssetIndex:
+		return "index"
+	case AssetETF:
+		return "etf"
+	case AssetBond:
+		return "bond"
+	case AssetBShare:
+		return "bshare"
+	case AssetREIT:
+		return "reit"
+	default:
+		return "unknown"
+	}
+}
+
+// Name 中文名称
+func (this AssetClass) Name() string {
+	switch this {
+	case AssetStock:
+		return "股票"
+	case AssetIndex:
+		return "指数"
+	case AssetETF:
+		return "基金"
+	case AssetBond:
+		return "债券"
+	case AssetBShare:
+		return "B股"
+	case AssetREIT:
+		return "REITs"
+	default:
+		return "未知"
+	}
+}
+
+// Symbol 证券代码,交易所+6位代码,例sz000001,通过ParseSymbol解析
+type Symbol struct {
+	Exchange Exchange //交易所
+	Code     string   //6位代码
+}
+
+// String 通达信的格式,例sz000001,客户端的方法都使用这个格式
+func (this Symbol) String() string {
+	return this.Exchange.String() + this.Code
+}
+
+// Suffix 后缀的格式,例000001.SZ
+func (this Symbol) Suffix() string {
+	return this.Code + "." + strings.ToUpper(this.Exchange.String())
+}
+
+// Secid 东方财富的格式,例0.000001,上海是1,深圳和北京是0
+func (this Symbol) Secid() string {
+	if this.Exchange == ExchangeSH {
+		return "1." + this.Code
+	}
+	return "0." + this.Code
+}
+
+// Type 证券类型,见SecurityTypeOf
+func (this Symbol) Type() SecurityType {
+	return SecurityTypeOf(this.String())
+}
+
+// Class 资产类别,由证券类型归类得到,见SecurityType.Class
+func (this Symbol) Class() AssetClass {
+	return this.Type().Class()
+}
+
+// IsZero 是否是零值
+func (this Symbol) IsZero() bool {
+	return this.Code == ""
+}
+
+// NewSymbol 根据交易所和6位代码生成
+func NewSymbol(exchange Exchange, code string) Symbol {
+	return Symbol{Exchange: exchange, Code: code}
+}
+
+/*
+ParseSymbol 解析各种常见格式的代码,不区分大小写,支持:
+sz000001,SZ000001 交易所前缀
+000001.SZ 交易所后缀
+1.000001 东方财富的secid,1是上海,0是深圳(北京的代码按规则识别)
+index:000001 指数,6位代码按指数的规则确定交易所,000是上海,399是深圳,899是北京
+000001 只有6位代码时按AddPrefix的规则推断,000001是平安银行,推断不出来返回错误
+*/
+func ParseSymbol(s string) (Symbol, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "index:"):
+		return ParseIndexSymbol(s[len("index:"):])
+
+	case len(s) == 9 && s[6] == '.' && isDigits(s[:6]):
+		if exchange, ok := exchangeOf(s[7:]); ok {
+			return NewSymbol(exchange, s[:6]), nil
+		}
+
+	case len(s) == 8 && s[1] == '.' && isDigits(s[2:]):
+		switch s[0] {
+		case '1':
+			return NewSymbol(ExchangeSH, s[2:]), nil
+		case '0':
+			if IsBJStock(ExchangeBJ.String()+s[2:]) || strings.HasPrefix(s[2:], "899") {
+				return NewSymbol(ExchangeBJ, s[2:]), nil
+			}
+			return NewSymbol(ExchangeSZ, s[2:]), nil
+		}
+
+	case len(s) == 8 && isDigits(s[2:]):
+		if exchange, ok := exchangeOf(s[:2]); ok {
+			return NewSymbol(exchange, s[2:]), nil
+		}
+
+	case len(s) == 6 && isDigits(s):
+		if code := AddPrefix(s); len(code) == 8 {
+			return ParseSymbol(code)
+		}
+		return Symbol{}, fmt.Errorf("%w[%s],无法确定交易所,请加上前缀,例如:sh%s", ErrInvalidCode, s, s)
+	}
+	return Symbol{}, fmt.Errorf("%w[%s],例如:sz000001,000001.SZ,1.600000", ErrInvalidCode, s)
+}
+
+// ParseIndexSymbol 同ParseSymbol,只有6位代码时按指数处理,例000001是上证指数
+func ParseIndexSymbol(s string) (Symbol, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 6 && isDigits(s) {
+		switch {
+		case strings.HasPrefix(s, "000") || strings.HasPrefix(s, "880") || strings.HasPrefix(s, "999"):
+			return NewSymbol(ExchangeSH, s), nil
+		case strings.HasPrefix(s, "399"):
+			return NewSymbol(ExchangeSZ, s), nil
+		case strings.HasPrefix(s, "899"):
+			return NewSymbol(ExchangeBJ, s), nil
+		}
+	}
+	return ParseSymbol(s)
+}
+
+func exchangeOf(s string) (Exchange, bool) {
+	switch s {
+	case ExchangeSH.String():
+		return ExchangeSH, true
+	case ExchangeSZ.String():
+		return ExchangeSZ, true
+	case ExchangeBJ.String():
+		return ExchangeBJ, true
+	}
+	return 0, false
+}
+
+func isDigits(s string) bool {
+	for _, v := range s {
+		if v < '0' || v > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
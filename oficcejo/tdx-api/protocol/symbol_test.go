@@ -0,0 +1,79 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func TestParseSymbol(t *testing.T) {
+	cases := map[string]struct {
+		code  string
+		class AssetClass
+	}{
+		"sz000001":     {"sz000001", AssetStock},
+		"SZ000001":     {"sz000001", AssetStock},
+		" 000001.SZ ":  {"sz000001", AssetStock},
+		"000001.sh":    {"sh000001", AssetIndex},
+		"1.000001":     {"sh000001", AssetIndex},
+		"0.000001":     {"sz000001", AssetStock},
+		"0.430047":     {"bj430047", AssetStock},
+		"000001":       {"sz000001", AssetStock},
+		"index:000001": {"sh000001", AssetIndex},
+		"INDEX:399001": {"sz399001", AssetIndex},
+		"600000":       {"sh600000", AssetStock},
+		"510300":       {"sh510300", AssetETF},
+		"sz159915":     {"sz159915", AssetETF},
+		"sh113050":     {"sh113050", AssetBond},
+		"sz123001":     {"sz123001", AssetBond},
+		"sh900901":     {"sh900901", AssetBShare},
+		"sz200002":     {"sz200002", AssetBShare},
+		"sh508000":     {"sh508000", AssetREIT},
+		"sz180101":     {"sz180101", AssetREIT},
+		"bj899050":     {"bj899050", AssetIndex},
+	}
+	for in, want := range cases {
+		s, err := ParseSymbol(in)
+		if err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if s.String() != want.code || s.Class() != want.class {
+			t.Errorf("%s: 预期%s(%s),得到%s(%s)", in, want.code, want.class, s, s.Class())
+		}
+	}
+
+	for _, in := range []string{"", "113050", "xx000001", "2.000001", "000001.HK", "sz00001a"} {
+		if s, err := ParseSymbol(in); err == nil {
+			t.Errorf("%s: 预期错误,得到%s", in, s)
+		}
+	}
+
+	s, _ := ParseSymbol("600000")
+	if s.Suffix() != "600000.SH" || s.Secid() != "1.600000" {
+		t.Errorf("预期600000.SH,1.600000,得到%s,%s", s.Suffix(), s.Secid())
+	}
+	if s, _ := ParseIndexSymbol("000001"); s.String() != "sh000001" {
+		t.Errorf("预期sh000001,得到%s", s)
+	}
+}
+
+func TestSecurityTypeOf(t *testing.T) {
+	for code, want := range map[string]SecurityType{
+		"sh600000": SecurityStock, "sh688001": SecurityStock, "sz000001": SecurityStock, "sz300750": SecurityStock,
+		"bj430047": SecurityStock, "bj920001": SecurityStock, "sh000001": SecurityIndex, "sz399001": SecurityIndex,
+		"bj899050": SecurityIndex, "sh880001": SecurityIndex, "sh510300": SecurityETF, "sz159915": SecurityETF,
+		"sh588000": SecurityETF, "sz161725": SecurityLOF, "sh501018": SecurityLOF, "sh508000": SecurityREIT,
+		"sz180101": SecurityREIT, "sh113050": SecurityCBond, "sz123001": SecurityCBond, "sz128001": SecurityCBond,
+		"sh204001": SecurityRepo, "sz131810": SecurityRepo, "sh019547": SecurityBond, "sz101001": SecurityBond,
+		"sh900901": SecurityBShare, "sz200002": SecurityBShare, "sz000001x": SecurityOther,
+	} {
+		if got := SecurityTypeOf(code); got != want {
+			t.Errorf("%s: 预期%s,得到%s", code, want, got)
+		}
+	}
+	if v, ok := ParseSecurityType(" ETF "); !ok || v != SecurityETF {
+		t.Errorf("预期etf,得到%s", v)
+	}
+	if _, ok := ParseSecurityType("xxx"); ok {
+		t.Error("预期未知类型")
+	}
+}
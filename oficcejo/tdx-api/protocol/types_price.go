@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"fmt"
+	"math"
+	"strings"
 )
 
 // Price 价格，单位厘
@@ -19,6 +21,49 @@ func (this Price) String() string {
 	return fmt.Sprintf("%s元", FloatUnitString(this.Float64()))
 }
 
+/*
+PriceDecimal 根据代码推断服务器返回的价格的小数位,例sz159558,准确的小数位见Code.Decimal
+股票,指数,深圳B股是2位,基金,债券,REITs,回购,上海B股是3位
+*/
+func PriceDecimal(code string) int8 {
+	if len(code) != 8 {
+		return 2
+	}
+	code = strings.ToLower(code)
+	switch code[:2] {
+	case ExchangeSH.String():
+		switch code[2:3] {
+		case "5", "1", "9", "2":
+			return 3
+		}
+	case ExchangeSZ.String():
+		switch code[2:4] {
+		case "15", "16", "18", "12", "13":
+			return 3
+		}
+	}
+	return 2
+}
+
+// ScalePrice 把服务器返回的价格(单位是10^-decimal元)转换成厘
+func ScalePrice(p Price, decimal int8) Price {
+	switch {
+	case decimal < 3:
+		return p * Price(math.Pow10(int(3-decimal)))
+	case decimal > 3:
+		return p / Price(math.Pow10(int(decimal-3)))
+	}
+	return p
+}
+
+// decimalOf 优先使用指定的小数位,为0时根据代码推断
+func decimalOf(code string, decimal int8) int8 {
+	if decimal > 0 {
+		return decimal
+	}
+	return PriceDecimal(code)
+}
+
 type PriceLevel struct {
 	Buy    bool  //买卖
 	Price  Price //价 想买卖的价格
@@ -48,21 +48,13 @@ func UTF8ToGBK(text []byte) []byte {
 	return bytes.ReplaceAll(content, []byte{0x00}, []byte{})
 }
 
+// DecodeCode 解析代码的交易所和6位代码,支持的格式见ParseSymbol
 func DecodeCode(code string) (Exchange, string, error) {
-	code = AddPrefix(code)
-	if len(code) != 8 {
-		return 0, "", fmt.Errorf("股票代码长度错误,例如:SZ000001")
-	}
-	switch strings.ToLower(code[:2]) {
-	case ExchangeSH.String():
-		return ExchangeSH, code[2:], nil
-	case ExchangeSZ.String():
-		return ExchangeSZ, code[2:], nil
-	case ExchangeBJ.String():
-		return ExchangeBJ, code[2:], nil
-	default:
-		return 0, "", fmt.Errorf("股票代码错误,例如:SZ000001")
+	s, err := ParseSymbol(code)
+	if err != nil {
+		return 0, "", err
 	}
+	return s.Exchange, s.Code, nil
 }
 
 func FloatUnit(f float64) (float64, string) {
@@ -126,22 +118,6 @@ func GetTime(bs [4]byte, Type uint8) time.Time {
 	}
 }
 
-func basePrice(code string) Price {
-	if len(code) < 2 {
-		return 1
-	}
-	switch code[:1] {
-	case "8":
-		return 1
-	}
-	switch code[:2] {
-	case "60", "30", "68", "00", "92", "43", "39":
-		return 1
-	default:
-		return 1
-	}
-}
-
 func getVolume(val uint32) (volume float64) {
 	ivol := int32(val)
 	logpoint := ivol >> (8 * 3)
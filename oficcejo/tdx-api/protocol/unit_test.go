@@ -23,3 +23,23 @@ func Test_getVolume(t *testing.T) {
 	t.Log(getVolume2(1237966432))
 
 }
+
+func TestPriceDecimal(t *testing.T) {
+	for code, want := range map[string]int8{
+		"sz000001": 2, "sh600000": 2, "sh000001": 2, "sz399001": 2, "bj920001": 2, "sz200002": 2,
+		"sh510300": 3, "sz159915": 3, "sh113050": 3, "sz123001": 3, "sh900901": 3, "sz180101": 3,
+	} {
+		if got := PriceDecimal(code); got != want {
+			t.Errorf("%s: 预期%d,得到%d", code, want, got)
+		}
+	}
+	if p := ScalePrice(1234, 2); p != 12340 {
+		t.Errorf("预期12340,得到%d", p)
+	}
+	if p := ScalePrice(1234, 3); p != 1234 {
+		t.Errorf("预期1234,得到%d", p)
+	}
+	if p := ScalePrice(12345, 4); p != 1234 {
+		t.Errorf("预期1234,得到%d", p)
+	}
+}
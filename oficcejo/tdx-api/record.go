@@ -0,0 +1,181 @@
+package tdx
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/injoyai/ios"
+	"github.com/injoyai/ios/client"
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx/protocol"
+)
+
+// Capture 抓包记录,一条请求帧或原始响应帧
+type Capture struct {
+	Time  time.Time `json:"time"`  //记录时间
+	Send  bool      `json:"send"`  //true是请求,false是响应
+	MsgID uint32    `json:"msgID"` //消息ID
+	Type  uint16    `json:"type"`  //请求/响应类型
+	Bytes string    `json:"bytes"` //原始字节的HEX,响应是未解压的
+}
+
+// WithRecord 把每个请求帧和原始响应帧记录到w,每行一条Capture的json,
+// 用于在生产环境抓取解析异常的原始数据,再通过NewReplayDial回放复现
+func WithRecord(w io.Writer) client.Option {
+	mu := sync.Mutex{}
+	record := func(send bool, bs []byte) {
+		c := &Capture{Time: time.Now(), Send: send, Bytes: hex.EncodeToString(bs)}
+		if send {
+			if f, err := protocol.DecodeFrame(bs); err == nil {
+				c.MsgID, c.Type = f.MsgID, f.Type
+			}
+		} else if f, _ := protocol.Decode(bs); f != nil {
+			c.MsgID, c.Type = f.MsgID, f.Type
+		}
+		line, _ := json.Marshal(c)
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			logs.Err(err)
+		}
+	}
+	return func(c *client.Client) {
+		onWriteWith := c.Event.OnWriteWith
+		c.Event.OnWriteWith = func(p []byte) (_ []byte, err error) {
+			if onWriteWith != nil {
+				if p, err = onWriteWith(p); err != nil {
+					return nil, err
+				}
+			}
+			record(true, p)
+			return p, nil
+		}
+		readFrom := c.Event.OnReadFrom
+		if readFrom == nil {
+			readFrom = protocol.ReadFrom
+		}
+		c.Event.OnReadFrom = func(r io.Reader) ([]byte, error) {
+			bs, err := readFrom(r)
+			if err == nil {
+				record(false, bs)
+			}
+			return bs, err
+		}
+	}
+}
+
+// LoadCaptures 读取WithRecord记录的数据
+func LoadCaptures(r io.Reader) ([]*Capture, error) {
+	ls := []*Capture(nil)
+	scan := bufio.NewScanner(r)
+	scan.Buffer(nil, 1<<24)
+	for scan.Scan() {
+		if len(scan.Bytes()) == 0 {
+			continue
+		}
+		c := new(Capture)
+		if err := json.Unmarshal(scan.Bytes(), c); err != nil {
+			return nil, err
+		}
+		ls = append(ls, c)
+	}
+	return ls, scan.Err()
+}
+
+// NewReplayDial 回放WithRecord记录的数据,配合DialWith使用,不需要网络
+// 请求按类型和内容(不含消息ID)匹配记录的响应,相同的请求按记录的顺序依次响应,超出记录次数时重复最后一次,
+// 没有记录的请求响应服务器拒绝
+func NewReplayDial(filename string) ios.DialFunc {
+	return func(ctx context.Context) (ios.ReadWriteCloser, string, error) {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		ls, err := LoadCaptures(f)
+		if err != nil {
+			return nil, "", err
+		}
+		r, err := newReplay(ls)
+		if err != nil {
+			return nil, "", err
+		}
+		c, s := net.Pipe()
+		go r.serve(s)
+		return c, "replay:" + filename, nil
+	}
+}
+
+type replay struct {
+	resp map[string][][]byte //请求的类型和内容 -> 按顺序的原始响应
+}
+
+func replayKey(f *protocol.Frame) string {
+	return fmt.Sprintf("%04x:%x", f.Type, f.Data)
+}
+
+func newReplay(ls []*Capture) (*replay, error) {
+	r := &replay{resp: map[string][][]byte{}}
+	req := map[string]string{} //消息ID和类型 -> 请求的类型和内容,心跳等请求的消息ID都是0,所以需要加上类型
+	for _, v := range ls {
+		bs, err := hex.DecodeString(v.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		id := fmt.Sprintf("%d:%d", v.MsgID, v.Type)
+		if v.Send {
+			f, err := protocol.DecodeFrame(bs)
+			if err != nil {
+				return nil, err
+			}
+			req[id] = replayKey(f)
+		} else if key, ok := req[id]; ok {
+			r.resp[key] = append(r.resp[key], bs)
+		}
+	}
+	return r, nil
+}
+
+func (this *replay) next(key string) []byte {
+	ls := this.resp[key]
+	if len(ls) == 0 {
+		return nil
+	}
+	if len(ls) > 1 {
+		this.resp[key] = ls[1:]
+	}
+	return ls[0]
+}
+
+func (this *replay) serve(c net.Conn) {
+	defer c.Close()
+	for {
+		bs, err := protocol.ReadRequestFrom(c)
+		if err != nil {
+			return
+		}
+		f, err := protocol.DecodeFrame(bs)
+		if err != nil {
+			return
+		}
+		resp := append([]byte(nil), this.next(replayKey(f))...)
+		if len(resp) < 16 {
+			//没有记录,按服务器拒绝响应
+			resp = make([]byte, 16)
+			copy(resp, []byte{0xB1, 0xCB, 0x74, 0x00, 0x0C})
+			copy(resp[10:], protocol.Bytes(f.Type))
+		}
+		copy(resp[5:9], protocol.Bytes(f.MsgID))
+		if _, err = c.Write(resp); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tdx_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	filename := filepath.Join(t.TempDir(), "capture.jsonl")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := tdx.Dial(s.Addr(), tdx.WithDebug(false), tdx.WithRecord(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.GetQuote("sz000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kline, err := c.GetKlineDay("sz000001", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	file.Close()
+
+	//回放不需要服务器
+	s.Close()
+	r, err := tdx.DialWith(tdx.NewReplayDial(filename), tdx.WithDebug(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := r.GetQuote("sz000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].K != want[0].K || got[0].BuyLevel != want[0].BuyLevel {
+		t.Errorf("quote: 预期%v,得到%v", want[0], got[0])
+	}
+	got2, err := r.GetKlineDay("sz000001", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got2.List) != len(kline.List) || got2.List[9].Close != kline.List[9].Close {
+		t.Errorf("kline: 预期%v,得到%v", kline.List, got2.List)
+	}
+
+	//没有记录的请求
+	if _, err := r.GetCount(protocol.ExchangeSZ); !errors.Is(err, tdx.ErrServerRejected) {
+		t.Errorf("预期ErrServerRejected,得到%v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package tdxtest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"math"
+	"time"
+
+	"github.com/injoyai/tdx/protocol"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	// ControlOK 响应成功的控制码
+	ControlOK = 0x1C
+
+	// ControlReject 服务器拒绝的控制码,配合空数据域使用
+	ControlReject = 0x0C
+
+	// zipMin 数据域达到该长度才进行压缩,和真实服务器一样,小数据直接明文返回
+	zipMin = 32
+)
+
+// EncodeResponse 按响应帧格式编码,数据域达到一定长度时使用zlib压缩
+func EncodeResponse(control uint8, msgID uint32, Type uint16, data []byte) []byte {
+	body := data
+	if len(data) >= zipMin {
+		buf := bytes.NewBuffer(nil)
+		w := zlib.NewWriter(buf)
+		w.Write(data)
+		w.Close()
+		body = buf.Bytes()
+	}
+	bs := make([]byte, 0, 16+len(body))
+	bs = append(bs, 0xB1, 0xCB, 0x74, 0x00)
+	bs = append(bs, control)
+	bs = append(bs, protocol.Bytes(msgID)...)
+	bs = append(bs, 0x00)
+	bs = append(bs, protocol.Bytes(Type)...)
+	bs = append(bs, protocol.Bytes(uint16(len(body)))...)
+	bs = append(bs, protocol.Bytes(uint16(len(data)))...)
+	return append(bs, body...)
+}
+
+// putInt 按变长格式写入整数,和protocol.GetPrice,protocol.CutInt互逆
+// 第一字节的第一位表示是否有后续数据,第二位表示正负,有效数据为后6位,后续字节有效数据为后7位
+func putInt(bs []byte, n int64) []byte {
+	b := byte(0)
+	if n < 0 {
+		b = 0x40
+		n = -n
+	}
+	b |= byte(n & 0x3F)
+	n >>= 6
+	for n > 0 {
+		bs = append(bs, b|0x80)
+		b = byte(n & 0x7F)
+		n >>= 7
+	}
+	return append(bs, b)
+}
+
+// putFloat 写入成交量,成交额等浮点数,服务器实际上是float32的小端字节
+func putFloat(bs []byte, f float64) []byte {
+	return append(bs, protocol.Bytes(math.Float32bits(float32(f)))...)
+}
+
+// putGBK 写入固定长度的GBK字符串,不足补0
+func putGBK(bs []byte, s string, length int) []byte {
+	b, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	buf := make([]byte, length)
+	copy(buf, b)
+	return append(bs, buf...)
+}
+
+// putKlineTime 写入k线时间,和protocol.GetTime互逆
+func putKlineTime(bs []byte, t time.Time, Type uint8) []byte {
+	if isMinuteKline(Type) {
+		ymd := uint16((t.Year()-2004)<<11 + int(t.Month())*100 + t.Day())
+		hm := uint16(t.Hour()*60 + t.Minute())
+		bs = append(bs, protocol.Bytes(ymd)...)
+		return append(bs, protocol.Bytes(hm)...)
+	}
+	return append(bs, protocol.Bytes(uint32(t.Year()*10000+int(t.Month())*100+t.Day()))...)
+}
+
+func isMinuteKline(Type uint8) bool {
+	switch Type {
+	case protocol.TypeKlineMinute, protocol.TypeKlineMinute2, protocol.TypeKline5Minute,
+		protocol.TypeKline15Minute, protocol.TypeKline30Minute, protocol.TypeKline60Minute:
+		return true
+	}
+	return false
+}
+
+// hourMinute 分笔成交的时间,当日的分钟数
+func hourMinute(t time.Time) []byte {
+	return protocol.Bytes(uint16(t.Hour()*60 + t.Minute()))
+}
+
+// priceUnit 盘口,分时和分笔的价格单位(厘),股票是分,基金和债券是厘,见protocol.PriceDecimal
+func priceUnit(code string) protocol.Price {
+	return protocol.ScalePrice(1, protocol.PriceDecimal(code))
+}
+
+// klineUnit k线价格比盘口多一位小数,返回厘转换成k线价格单位的倍数,股票和指数是1,基金和债券是10
+func klineUnit(code string) protocol.Price {
+	return protocol.Price(math.Pow10(int(protocol.PriceDecimal(code)) - 2))
+}
+
+// putMinutes 分时点 价格和均价都是相对上个点的差值,单位见priceUnit
+func putMinutes(bs []byte, ls []protocol.PriceNumber, unit protocol.Price) []byte {
+	price, avg := int64(0), int64(0)
+	for _, v := range ls {
+		bs = putInt(bs, int64(v.Price/unit)-price)
+		bs = putInt(bs, int64(v.AvgPrice/unit)-avg)
+		bs = putInt(bs, int64(v.Number))
+		price, avg = int64(v.Price/unit), int64(v.AvgPrice/unit)
+	}
+	return bs
+}
+
+// quoteTime 盘口时间编码成HHMMxxxx,xxxx是分钟内的进度(万分比),零值编码成0
+func quoteTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	ns := int64(t.Second())*int64(time.Second) + int64(t.Nanosecond())
+	frac := (ns*10000 + int64(time.Minute)/2) / int64(time.Minute)
+	return int64(t.Hour())*1000000 + int64(t.Minute())*10000 + frac
+}
+
+// pageOf 按通达信的分页方式截取,start是从最新一条往前的偏移,返回结果按时间正序
+func pageOf(n int, start, count uint16) (int, int) {
+	end := n - int(start)
+	if end < 0 {
+		end = 0
+	}
+	begin := end - int(count)
+	if begin < 0 {
+		begin = 0
+	}
+	return begin, end
+}
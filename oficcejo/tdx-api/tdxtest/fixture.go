@@ -0,0 +1,185 @@
+package tdxtest
+
+import (
+	"time"
+
+	"github.com/injoyai/tdx/protocol"
+)
+
+// Fixture 模拟服务器应答用的固定数据,代码统一使用带前缀的小写格式,例sz000001
+// 价格单位和protocol一致为厘,分时,分笔和盘口的价格精度只到分,基金和债券到厘
+type Fixture struct {
+	Codes          map[protocol.Exchange][]*protocol.Code       //代码列表,按交易所区分
+	Quotes         map[string]*protocol.Quote                   //盘口,key例sz000001
+	Klines         map[string]protocol.Klines                   //k线,按时间正序,所有k线类型共用
+	Minutes        map[string][]protocol.PriceNumber            //当日分时
+	HistoryMinutes map[string]map[string][]protocol.PriceNumber //历史分时,key为日期(20060102)和代码
+	Trades         map[string]protocol.Trades                   //当日分笔成交,按时间正序
+	HistoryTrades  map[string]map[string]protocol.Trades        //历史分笔成交,key为日期(20060102)和代码
+	Info           string                                       //建立连接时返回的服务器信息
+}
+
+// DefaultFixture 默认的固定数据,包含平安银行,浦发银行,上证指数和沪深300ETF(价格3位小数)
+func DefaultFixture() *Fixture {
+	date := time.Date(2024, 11, 15, 0, 0, 0, 0, time.Local)
+	f := &Fixture{
+		Codes: map[protocol.Exchange][]*protocol.Code{
+			protocol.ExchangeSZ: {
+				{Code: "000001", Name: "平安银行", Multiple: 100, Decimal: 2},
+			},
+			protocol.ExchangeSH: {
+				{Code: "000001", Name: "上证指数", Multiple: 100, Decimal: 2, LastPrice: 3330.5},
+				{Code: "600000", Name: "浦发银行", Multiple: 100, Decimal: 2},
+				{Code: "510300", Name: "沪深300ETF", Multiple: 100, Decimal: 3},
+			},
+		},
+		Quotes:         map[string]*protocol.Quote{},
+		Klines:         map[string]protocol.Klines{},
+		Minutes:        map[string][]protocol.PriceNumber{},
+		HistoryMinutes: map[string]map[string][]protocol.PriceNumber{},
+		Trades:         map[string]protocol.Trades{},
+		HistoryTrades:  map[string]map[string]protocol.Trades{},
+		Info:           "tdxtest 本地模拟服务器",
+	}
+
+	for code, base := range map[string]protocol.Price{
+		"sz000001": 11500,
+		"sh600000": 10200,
+		"sh000001": 3330500,
+		"sh510300": 3957,
+	} {
+		f.Klines[code] = fixtureKlines(date, base, 30, isIndex(code))
+		f.Quotes[code] = fixtureQuote(code, base)
+		f.Minutes[code] = fixtureMinutes(time.Now().In(protocol.LocationCST), base)
+		f.Trades[code] = fixtureTrades(time.Now().In(protocol.LocationCST), base)
+		f.SetHistoryMinute(date.Format("20060102"), code, fixtureMinutes(date, base))
+		f.SetHistoryTrade(date.Format("20060102"), code, fixtureTrades(date, base))
+	}
+
+	return f
+}
+
+// SetHistoryMinute 设置某天的历史分时
+func (this *Fixture) SetHistoryMinute(date, code string, ls []protocol.PriceNumber) {
+	if this.HistoryMinutes[date] == nil {
+		this.HistoryMinutes[date] = map[string][]protocol.PriceNumber{}
+	}
+	this.HistoryMinutes[date][code] = ls
+}
+
+// SetHistoryTrade 设置某天的历史分笔成交
+func (this *Fixture) SetHistoryTrade(date, code string, ls protocol.Trades) {
+	if this.HistoryTrades[date] == nil {
+		this.HistoryTrades[date] = map[string]protocol.Trades{}
+	}
+	this.HistoryTrades[date][code] = ls
+}
+
+// fixtureKlines 生成截止到end的n根日线,价格在base附近小幅波动
+func fixtureKlines(end time.Time, base protocol.Price, n int, index bool) protocol.Klines {
+	ls := protocol.Klines{}
+	last := base
+	for i := 0; i < n; i++ {
+		open := last + protocol.Price(i%3-1)*10
+		k := &protocol.Kline{
+			Time:   end.AddDate(0, 0, i-n+1).Add(15 * time.Hour),
+			Last:   last,
+			Open:   open,
+			High:   open + 60,
+			Low:    open - 40,
+			Close:  open + protocol.Price(i%5-2)*10,
+			Volume: int64(1000 + i*100),
+			Amount: protocol.Price(1000+i*100) * 1000,
+		}
+		if index {
+			k.Volume *= 100
+			k.UpCount = 1000 + i
+			k.DownCount = 2000 - i
+		}
+		last = k.Close
+		ls = append(ls, k)
+	}
+	return ls
+}
+
+func fixtureQuote(code string, base protocol.Price) *protocol.Quote {
+	//盘口时间只有时分秒,客户端按北京时间的当天解析
+	now := time.Now().In(protocol.LocationCST)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	q := &protocol.Quote{
+		K: protocol.K{
+			Last:  base - 100,
+			Open:  base - 50,
+			High:  base + 100,
+			Low:   base - 150,
+			Close: base,
+		},
+		ServerTime:     today.Add(14*time.Hour + 59*time.Minute + 35178*time.Millisecond),
+		ReversedBytes1: -int(base / priceUnit(code)),
+		RiseSpeed:      0.35,
+		TotalHand:      123456,
+		Intuition:      321,
+		Amount:         141966336,
+		InsideDish:     60000,
+		OuterDisc:      63456,
+	}
+	q.Exchange, q.Code, _ = protocol.DecodeCode(code)
+	q.EstimatedLimitUp, q.EstimatedLimitDown = protocol.LimitPrice(code, q.K.Last, false)
+	for i := 0; i < 5; i++ {
+		q.BuyLevel[i] = protocol.PriceLevel{Buy: true, Price: base - protocol.Price(i+1)*10, Number: 100 * (i + 1)}
+		q.SellLevel[i] = protocol.PriceLevel{Price: base + protocol.Price(i+1)*10, Number: 200 * (i + 1)}
+	}
+	return q
+}
+
+// fixtureMinutes 生成某天240个分时点,均价按成交量加权,精度到分
+func fixtureMinutes(date time.Time, base protocol.Price) []protocol.PriceNumber {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 9, 30, 0, 0, protocol.LocationCST)
+	ls := make([]protocol.PriceNumber, 240)
+	amount, number := protocol.Price(0), 0
+	for i := range ls {
+		t := start.Add(time.Duration(i+1) * time.Minute)
+		if i >= 120 {
+			t = t.Add(90 * time.Minute)
+		}
+		ls[i] = protocol.PriceNumber{
+			Time:   t,
+			Price:  base + protocol.Price(i%7-3)*10,
+			Number: 100 + i,
+		}
+		amount += ls[i].Price * protocol.Price(ls[i].Number)
+		number += ls[i].Number
+		ls[i].AvgPrice = amount / protocol.Price(number) / 10 * 10
+	}
+	return ls
+}
+
+// fixtureTrades 生成当天上午的分笔成交,每分钟一笔
+func fixtureTrades(date time.Time, base protocol.Price) protocol.Trades {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 9, 30, 0, 0, date.Location())
+	ls := protocol.Trades{}
+	for i := 0; i < 120; i++ {
+		ls = append(ls, &protocol.Trade{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Price:  base + protocol.Price(i%5-2)*10,
+			Volume: 10 + i,
+			Status: i % 2,
+			Number: 1 + i%4,
+		})
+	}
+	return ls
+}
+
+// isIndex 是否是指数代码,指数的k线会多4字节的涨跌家数
+func isIndex(code string) bool {
+	if len(code) != 8 {
+		return false
+	}
+	switch code[:2] {
+	case protocol.ExchangeSH.String():
+		return code[2:5] == "000" || code[2:5] == "880" || code[2:5] == "999"
+	case protocol.ExchangeSZ.String():
+		return code[2:5] == "399"
+	}
+	return false
+}
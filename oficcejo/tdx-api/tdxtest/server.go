@@ -0,0 +1,405 @@
+package tdxtest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/injoyai/conv"
+	"github.com/injoyai/logs"
+	"github.com/injoyai/tdx/protocol"
+)
+
+// ErrNotSupport 没有对应的处理函数,服务器以拒绝的方式响应
+var ErrNotSupport = errors.New("模拟服务器不支持该请求")
+
+// HandlerFunc 自定义请求的处理,返回未压缩的数据域,返回错误时服务器以拒绝的方式响应
+type HandlerFunc func(f *protocol.Frame) ([]byte, error)
+
+// Server 本地模拟的通达信行情服务器,按协议格式应答Fixture中的数据,
+// 用于离线测试,例 tdx.Dial(s.Addr())
+type Server struct {
+	Fixture *Fixture
+
+	listener net.Listener
+	handler  map[uint16]HandlerFunc
+	conns    map[net.Conn]struct{}
+	mu       sync.RWMutex
+	wg       sync.WaitGroup
+}
+
+// NewServer 在127.0.0.1的随机端口启动模拟服务器,fixture为nil时使用DefaultFixture,
+// 启动后不要再修改fixture
+func NewServer(fixture *Fixture) (*Server, error) {
+	if fixture == nil {
+		fixture = DefaultFixture()
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Fixture:  fixture,
+		listener: l,
+		handler:  map[uint16]HandlerFunc{},
+		conns:    map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Addr 监听地址,例127.0.0.1:52345
+func (this *Server) Addr() string {
+	return this.listener.Addr().String()
+}
+
+// Handle 自定义某个请求类型的处理,会覆盖默认处理,可用于模拟异常数据
+func (this *Server) Handle(Type uint16, h HandlerFunc) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.handler[Type] = h
+}
+
+// Close 关闭服务器和所有连接
+func (this *Server) Close() error {
+	err := this.listener.Close()
+	this.mu.Lock()
+	for c := range this.conns {
+		c.Close()
+	}
+	this.mu.Unlock()
+	this.wg.Wait()
+	return err
+}
+
+func (this *Server) run() {
+	defer this.wg.Done()
+	for {
+		c, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		this.mu.Lock()
+		this.conns[c] = struct{}{}
+		this.mu.Unlock()
+		this.wg.Add(1)
+		go this.serve(c)
+	}
+}
+
+func (this *Server) serve(c net.Conn) {
+	defer func() {
+		this.mu.Lock()
+		delete(this.conns, c)
+		this.mu.Unlock()
+		c.Close()
+		this.wg.Done()
+	}()
+	r := bufio.NewReader(c)
+	for {
+		f, err := ReadFrame(r)
+		if err != nil {
+			return
+		}
+		control := uint8(ControlOK)
+		data, err := this.deal(f)
+		if err != nil {
+			logs.Debugf("[tdxtest] 类型0x%04X: %v\n", f.Type, err)
+			control, data = ControlReject, nil
+		}
+		if _, err = c.Write(EncodeResponse(control, f.MsgID, f.Type, data)); err != nil {
+			return
+		}
+	}
+}
+
+// ReadFrame 读取客户端的请求帧,兼容标准行情(0c)和扩展行情(01)的帧头
+func ReadFrame(r io.Reader) (*protocol.Frame, error) {
+	bs, err := protocol.ReadRequestFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.DecodeFrame(bs)
+}
+
+func (this *Server) deal(f *protocol.Frame) ([]byte, error) {
+	this.mu.RLock()
+	h, ok := this.handler[f.Type]
+	this.mu.RUnlock()
+	if ok {
+		return h(f)
+	}
+
+	switch f.Type {
+	case protocol.TypeConnect:
+		return putGBK(make([]byte, 68), this.Fixture.Info, len(this.Fixture.Info)), nil
+	case protocol.TypeHeart:
+		return []byte{0x00}, nil
+	case protocol.TypeCount:
+		return this.count(f.Data)
+	case protocol.TypeCode:
+		return this.code(f.Data)
+	case protocol.TypeQuote:
+		return this.quote(f.Data)
+	case protocol.TypeKline:
+		return this.kline(f.Data)
+	case protocol.TypeMinute:
+		return this.minute(f.Data)
+	case protocol.TypeHistoryMinute:
+		return this.historyMinute(f.Data)
+	case protocol.TypeMinuteTrade:
+		return this.trade(f.Data)
+	case protocol.TypeHistoryMinuteTrade:
+		return this.historyTrade(f.Data)
+	}
+	return nil, ErrNotSupport
+}
+
+// fullCode 请求中的交易所和代码转成sz000001的格式
+func fullCode(exchange byte, code []byte) string {
+	return protocol.Exchange(exchange).String() + string(code)
+}
+
+func need(bs []byte, n int) error {
+	if len(bs) < n {
+		return fmt.Errorf("请求数据长度不足,预期%d,得到%d", n, len(bs))
+	}
+	return nil
+}
+
+/*
+count 请求: 交易所(1) 00 75c73301
+响应: 数量(2)
+*/
+func (this *Server) count(bs []byte) ([]byte, error) {
+	if err := need(bs, 2); err != nil {
+		return nil, err
+	}
+	n := len(this.Fixture.Codes[protocol.Exchange(bs[0])])
+	return protocol.Bytes(uint16(n)), nil
+}
+
+/*
+code 请求: 交易所(1) 00 起始(2)
+响应: 数量(2) + 每个代码29字节,代码(6) 倍数(2) 名称(8) 未知(4) 小数点(1) 昨收(4) 未知(4)
+*/
+func (this *Server) code(bs []byte) ([]byte, error) {
+	if err := need(bs, 4); err != nil {
+		return nil, err
+	}
+	ls := this.Fixture.Codes[protocol.Exchange(bs[0])]
+	start := int(protocol.Uint16(bs[2:4]))
+	if start > len(ls) {
+		start = len(ls)
+	}
+	ls = ls[start:]
+	if len(ls) > 1000 {
+		ls = ls[:1000]
+	}
+	data := protocol.Bytes(uint16(len(ls)))
+	for _, v := range ls {
+		code := make([]byte, 6)
+		copy(code, v.Code)
+		data = append(data, code...)
+		data = append(data, protocol.Bytes(v.Multiple)...)
+		data = putGBK(data, v.Name, 8)
+		data = append(data, make([]byte, 4)...)
+		data = append(data, byte(v.Decimal))
+		data = putFloat(data, v.LastPrice)
+		data = append(data, make([]byte, 4)...)
+	}
+	return data, nil
+}
+
+/*
+quote 请求: 05000000 00000000 数量(2) + 每个代码 交易所(1) 代码(6)
+响应: 未知(2) 数量(2) + 每个代码的盘口,价格都是相对收盘价的差值,单位见priceUnit
+*/
+func (this *Server) quote(bs []byte) ([]byte, error) {
+	if err := need(bs, 10); err != nil {
+		return nil, err
+	}
+	number := int(protocol.Uint16(bs[8:10]))
+	bs = bs[10:]
+	if err := need(bs, number*7); err != nil {
+		return nil, err
+	}
+	ls := []*protocol.Quote(nil)
+	for i := 0; i < number; i++ {
+		if q, ok := this.Fixture.Quotes[fullCode(bs[i*7], bs[i*7+1:i*7+7])]; ok {
+			ls = append(ls, q)
+		}
+	}
+
+	data := []byte{0x01, 0x36}
+	data = append(data, protocol.Bytes(uint16(len(ls)))...)
+	for _, q := range ls {
+		unit := priceUnit(q.Exchange.String() + q.Code)
+		closePrice := int64(q.K.Close / unit)
+		data = append(data, q.Exchange.Uint8())
+		data = append(data, q.Code...)
+		data = append(data, protocol.Bytes(q.Active1)...)
+		data = putInt(data, closePrice)
+		data = putInt(data, int64(q.K.Last/unit)-closePrice)
+		data = putInt(data, int64(q.K.Open/unit)-closePrice)
+		data = putInt(data, int64(q.K.High/unit)-closePrice)
+		data = putInt(data, int64(q.K.Low/unit)-closePrice)
+		data = putInt(data, quoteTime(q.ServerTime))
+		data = putInt(data, int64(q.ReversedBytes1))
+		data = putInt(data, int64(q.TotalHand))
+		data = putInt(data, int64(q.Intuition))
+		data = putFloat(data, q.Amount)
+		data = putInt(data, int64(q.InsideDish))
+		data = putInt(data, int64(q.OuterDisc))
+		data = putInt(data, int64(q.ReversedBytes2))
+		data = putInt(data, int64(q.ReversedBytes3))
+		for i := 0; i < 5; i++ {
+			data = putInt(data, int64(q.BuyLevel[i].Price/unit)-closePrice)
+			data = putInt(data, int64(q.SellLevel[i].Price/unit)-closePrice)
+			data = putInt(data, int64(q.BuyLevel[i].Number))
+			data = putInt(data, int64(q.SellLevel[i].Number))
+		}
+		data = append(data, protocol.Bytes(q.ReversedBytes4)...)
+		data = putInt(data, int64(q.ReversedBytes5))
+		data = putInt(data, int64(q.ReversedBytes6))
+		data = putInt(data, int64(q.ReversedBytes7))
+		data = putInt(data, int64(q.ReversedBytes8))
+		data = append(data, protocol.Bytes(uint16(int16(math.Round(q.RiseSpeed*100))))...)
+		data = append(data, protocol.Bytes(q.Active2)...)
+	}
+	return data, nil
+}
+
+/*
+kline 请求: 交易所(1) 00 代码(6) 类型(1) 00 0100 起始(2) 数量(2) 00*10
+响应: 数量(2) + 每根k线 时间(4) 开收高低(相对上一根收盘价,单位见klineUnit) 成交量(4) 成交额(4) [涨跌家数(4),仅指数]
+*/
+func (this *Server) kline(bs []byte) ([]byte, error) {
+	if err := need(bs, 16); err != nil {
+		return nil, err
+	}
+	code := fullCode(bs[0], bs[2:8])
+	Type := bs[8]
+	ls := this.Fixture.Klines[code]
+	begin, end := pageOf(len(ls), protocol.Uint16(bs[12:14]), protocol.Uint16(bs[14:16]))
+	ls = ls[begin:end]
+	index := isIndex(code)
+	unit := klineUnit(code)
+
+	data := protocol.Bytes(uint16(len(ls)))
+	last := protocol.Price(0)
+	for _, k := range ls {
+		data = putKlineTime(data, k.Time, Type)
+		data = putInt(data, int64((k.Open-last)*unit))
+		data = putInt(data, int64((k.Close-k.Open)*unit))
+		data = putInt(data, int64((k.High-k.Open)*unit))
+		data = putInt(data, int64((k.Low-k.Open)*unit))
+		last = k.Close
+
+		volume := float64(k.Volume)
+		if index {
+			volume /= 100
+		}
+		if isMinuteKline(Type) || Type == protocol.TypeKlineDay2 {
+			volume *= 100
+		}
+		data = putFloat(data, volume)
+		data = putFloat(data, float64(k.Amount)/1000)
+		if index {
+			data = append(data, protocol.Bytes(uint16(k.UpCount))...)
+			data = append(data, protocol.Bytes(uint16(k.DownCount))...)
+		}
+	}
+	return data, nil
+}
+
+/*
+minute 请求: 交易所(1) 00 代码(6) 00000000
+响应: 数量(2) 未知(2) + 每个点 价格(相对上个点,单位见priceUnit) 均价(相对上个点,单位见priceUnit) 数量
+*/
+func (this *Server) minute(bs []byte) ([]byte, error) {
+	if err := need(bs, 8); err != nil {
+		return nil, err
+	}
+	code := fullCode(bs[0], bs[2:8])
+	ls := this.Fixture.Minutes[code]
+	data := protocol.Bytes(uint16(len(ls)))
+	data = append(data, make([]byte, 2)...)
+	return putMinutes(data, ls, priceUnit(code)), nil
+}
+
+/*
+historyMinute 请求: 日期(4) 交易所(1) 代码(6)
+响应: 数量(2) 未知(4) + 每个点 价格(相对上个点,单位见priceUnit) 均价(相对上个点,单位见priceUnit) 数量
+*/
+func (this *Server) historyMinute(bs []byte) ([]byte, error) {
+	if err := need(bs, 11); err != nil {
+		return nil, err
+	}
+	date := conv.String(protocol.Uint32(bs[:4]))
+	code := fullCode(bs[4], bs[5:11])
+	ls := this.Fixture.HistoryMinutes[date][code]
+	data := protocol.Bytes(uint16(len(ls)))
+	data = append(data, make([]byte, 4)...)
+	return putMinutes(data, ls, priceUnit(code)), nil
+}
+
+/*
+trade 请求: 交易所(1) 00 代码(6) 起始(2) 数量(2)
+响应: 数量(2) + 每笔 时间(2) 价格(相对上一笔,单位见priceUnit) 成交量 单数 状态 未知
+*/
+func (this *Server) trade(bs []byte) ([]byte, error) {
+	if err := need(bs, 12); err != nil {
+		return nil, err
+	}
+	code := fullCode(bs[0], bs[2:8])
+	unit := priceUnit(code)
+	ls := this.Fixture.Trades[code]
+	begin, end := pageOf(len(ls), protocol.Uint16(bs[8:10]), protocol.Uint16(bs[10:12]))
+	ls = ls[begin:end]
+	data := protocol.Bytes(uint16(len(ls)))
+	last := int64(0)
+	for _, v := range ls {
+		data = append(data, hourMinute(v.Time)...)
+		data = putInt(data, int64(v.Price/unit)-last)
+		data = putInt(data, int64(v.Volume))
+		data = putInt(data, int64(v.Number))
+		data = putInt(data, int64(v.Status))
+		data = putInt(data, 0)
+		last = int64(v.Price / unit)
+	}
+	return data, nil
+}
+
+/*
+historyTrade 请求: 日期(4) 交易所(1) 00 代码(6) 起始(2) 数量(2)
+响应: 数量(2) 未知(4) + 每笔 时间(2) 价格(相对上一笔,单位见priceUnit) 成交量 状态 未知
+*/
+func (this *Server) historyTrade(bs []byte) ([]byte, error) {
+	if err := need(bs, 16); err != nil {
+		return nil, err
+	}
+	date := conv.String(protocol.Uint32(bs[:4]))
+	code := fullCode(bs[4], bs[6:12])
+	unit := priceUnit(code)
+	ls := this.Fixture.HistoryTrades[date][code]
+	begin, end := pageOf(len(ls), protocol.Uint16(bs[12:14]), protocol.Uint16(bs[14:16]))
+	ls = ls[begin:end]
+	data := protocol.Bytes(uint16(len(ls)))
+	data = append(data, make([]byte, 4)...)
+	last := int64(0)
+	for _, v := range ls {
+		data = append(data, hourMinute(v.Time)...)
+		data = putInt(data, int64(v.Price/unit)-last)
+		data = putInt(data, int64(v.Volume))
+		data = putInt(data, int64(v.Status))
+		data = putInt(data, 0)
+		last = int64(v.Price / unit)
+	}
+	return data, nil
+}
@@ -0,0 +1,165 @@
+package tdxtest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+	"github.com/injoyai/tdx/tdxtest"
+)
+
+func dial(t *testing.T) (*tdxtest.Server, *tdx.Client) {
+	s, err := tdxtest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//北交所代码不从官网获取,测试结果不受网络影响
+	c, err := tdx.Dial(s.Addr(), tdx.WithDebug(false), tdx.WithBjCodes(func() ([]*tdx.BjCode, error) { return nil, nil }))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return s, c
+}
+
+func TestServer(t *testing.T) {
+	s, c := dial(t)
+	fix := s.Fixture
+
+	count, err := c.GetCount(protocol.ExchangeSH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Count != 3 {
+		t.Errorf("count: 预期3,得到%d", count.Count)
+	}
+
+	codes, err := c.GetCodeAll(protocol.ExchangeSH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes.List) != 3 || codes.List[0].Name != "上证指数" || codes.List[0].LastPrice != 3330.5 {
+		t.Errorf("code: %v", codes.List)
+	}
+
+	//沪深300ETF的价格是3位小数
+	quotes, err := c.GetQuote("sz000001", "sh600000", "sh510300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range quotes {
+		want := fix.Quotes[q.Exchange.String()+q.Code]
+		if q.K != want.K || q.BuyLevel != want.BuyLevel || q.SellLevel != want.SellLevel ||
+			q.Amount != want.Amount || q.TotalHand != want.TotalHand || !q.ServerTime.Equal(want.ServerTime) ||
+			q.RiseSpeed != want.RiseSpeed || q.EstimatedLimitUp != want.EstimatedLimitUp || q.EstimatedLimitDown != want.EstimatedLimitDown || q.EstimatedSuspended {
+			t.Errorf("quote: 预期%v,得到%v", want, q)
+		}
+	}
+
+	//沪深300ETF的k线价格单位是0.1厘
+	for _, code := range []string{"sz000001", "sh510300"} {
+		kline, err := c.GetKlineDayAll(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fix.Klines[code]
+		if len(kline.List) != len(want) {
+			t.Fatalf("kline(%s): 预期%d条,得到%d条", code, len(want), len(kline.List))
+		}
+		for i, k := range kline.List {
+			w := want[i]
+			if !k.Time.Equal(w.Time) || k.Open != w.Open || k.Close != w.Close || k.High != w.High ||
+				k.Low != w.Low || k.Volume != w.Volume || k.Amount != w.Amount {
+				t.Errorf("kline(%s)[%d]: 预期%v,得到%v", code, i, w, k)
+			}
+		}
+	}
+
+	index, err := c.GetIndex(protocol.TypeKlineDay, "sh000001", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := fix.Klines["sh000001"][len(fix.Klines["sh000001"])-1]
+	if k := index.List[len(index.List)-1]; k.Volume != last.Volume || k.UpCount != last.UpCount || k.DownCount != last.DownCount {
+		t.Errorf("index: 预期%v,得到%v", last, k)
+	}
+
+	minute, err := c.GetHistoryMinute("20241115", "sz000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minute.List) != 240 || minute.List[0].Time.Format("20060102 15:04") != "20241115 09:31" ||
+		minute.List[239].Time.Format("15:04") != "15:00" {
+		t.Errorf("history minute: %v", minute.List)
+	}
+	for i, v := range minute.List {
+		if w := fix.HistoryMinutes["20241115"]["sz000001"][i]; !v.Time.Equal(w.Time) || v.Price != w.Price ||
+			v.AvgPrice != w.AvgPrice || v.Number != w.Number {
+			t.Errorf("history minute[%d]: 预期%v,得到%v", i, w, v)
+		}
+	}
+
+	today, err := c.GetMinute("sh510300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(today.List) != 240 {
+		t.Fatalf("minute: 预期240个,得到%d个", len(today.List))
+	}
+	for i, v := range today.List {
+		if w := fix.Minutes["sh510300"][i]; !v.Time.Equal(w.Time) || v.Price != w.Price ||
+			v.AvgPrice != w.AvgPrice || v.Number != w.Number {
+			t.Errorf("minute[%d]: 预期%v,得到%v", i, w, v)
+		}
+	}
+
+	trade, err := c.GetMinuteTradeAll("sh510300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trade.List) != len(fix.Trades["sh510300"]) {
+		t.Fatalf("trade: 预期%d条,得到%d条", len(fix.Trades["sh510300"]), len(trade.List))
+	}
+	for i, v := range trade.List {
+		w := fix.Trades["sh510300"][i]
+		if v.Time.Format("15:04") != w.Time.Format("15:04") || v.Price != w.Price ||
+			v.Volume != w.Volume || v.Number != w.Number || v.Status != w.Status {
+			t.Errorf("trade[%d]: 预期%v,得到%v", i, w, v)
+		}
+	}
+
+	history, err := c.GetHistoryMinuteTradeDay("20241115", "sh600000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.List) != 120 || history.List[0].Price != fix.HistoryTrades["20241115"]["sh600000"][0].Price {
+		t.Errorf("history trade: %v", history.List)
+	}
+}
+
+func TestServer_Handle(t *testing.T) {
+	s, c := dial(t)
+	c.SetTimeout(time.Second)
+
+	//未支持的请求以拒绝的方式响应
+	if _, err := c.GetXdXr("sz000001"); !errors.Is(err, tdx.ErrServerRejected) {
+		t.Errorf("预期ErrServerRejected,得到%v", err)
+	}
+
+	s.Handle(protocol.TypeCount, func(f *protocol.Frame) ([]byte, error) {
+		return protocol.Bytes(uint16(1234)), nil
+	})
+	count, err := c.GetCount(protocol.ExchangeSZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Count != 1234 {
+		t.Errorf("预期1234,得到%d", count.Count)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,10 +40,11 @@ func init() {
 		log.Printf("初始化代码库失败: %v", err)
 	} else {
 		tdx.DefaultCodes = codes
+		client.SetCodeResolver(codes)
 		if err := tdx.DefaultCodes.Update(); err != nil {
 			log.Printf("更新代码库失败: %v", err)
 		} else {
-			log.Printf("已加载股票代码，共 %d 条", len(tdx.DefaultCodes.Map))
+			log.Printf("已加载股票代码，共 %d 条", tdx.DefaultCodes.Len())
 		}
 	}
 
@@ -102,7 +104,12 @@ func handleGetQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quotes, err := client.GetQuote(codes...)
+	// 使用连接池的实时行情类别,拉取历史数据的任务不会占满连接
+	var quotes protocol.QuotesResp
+	err := manager.DoPriority(r.Context(), tdx.PriorityRealtime, func(c *tdx.Client) (err error) {
+		quotes, err = c.GetQuoteContext(r.Context(), codes...)
+		return err
+	})
 	if err != nil {
 		errorResponse(w, fmt.Sprintf("获取行情失败: %v", err))
 		return
@@ -164,8 +171,17 @@ func handleGetKline(w http.ResponseWriter, r *http.Request) {
 	successResponse(w, resp)
 }
 
-// getQfqKlineDay 获取前复权日K线数据
+// getQfqKlineDay 获取前复权日K线数据，根据通达信的除权除息信息在本地计算
 func getQfqKlineDay(code string) (*protocol.KlineResp, error) {
+	resp, err := client.GetKlineDayQfqAll(code)
+	if err != nil {
+		return nil, fmt.Errorf("获取前复权数据失败: %w", err)
+	}
+	return resp, nil
+}
+
+// getThsQfqKlineDay 获取同花顺的前复权日K线数据
+func getThsQfqKlineDay(code string) (*protocol.KlineResp, error) {
 	// 使用同花顺API获取前复权数据
 	klines, err := extend.GetTHSDayKline(code, extend.THS_QFQ)
 	if err != nil {
@@ -372,7 +388,7 @@ func handleGetTrade(w http.ResponseWriter, r *http.Request) {
 		resp, err = client.GetMinuteTrade(code, 0, 1800)
 	} else {
 		// 获取历史某天的分时成交
-		resp, err = client.GetHistoryMinuteTradeDay(date, code)
+		resp, err = client.GetHistoryMinuteTradeDayContext(r.Context(), date, code)
 	}
 
 	if err != nil {
@@ -383,6 +399,23 @@ func handleGetTrade(w http.ResponseWriter, r *http.Request) {
 	successResponse(w, resp)
 }
 
+// 获取当日集合竞价
+func handleGetAuction(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		errorResponse(w, "股票代码不能为空")
+		return
+	}
+
+	resp, err := client.GetAuction(code)
+	if err != nil {
+		errorResponse(w, fmt.Sprintf("获取集合竞价失败: %v", err))
+		return
+	}
+
+	successResponse(w, resp)
+}
+
 // 搜索股票代码
 func handleSearchCode(w http.ResponseWriter, r *http.Request) {
 	keyword := r.URL.Query().Get("keyword")
@@ -391,39 +424,32 @@ func handleSearchCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keywordUpper := strings.ToUpper(keyword)
-	results := []map[string]string{}
-	seen := map[string]struct{}{}
-
-	codeModels, err := getAllCodeModels()
+	//搜索默认包含全部类型
+	types, err := parseTypes(r, "all")
 	if err != nil {
-		errorResponse(w, "搜索失败: "+err.Error())
+		errorResponse(w, err.Error())
 		return
 	}
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 200 {
+		limit = v
+	}
 
-	for _, model := range codeModels {
-		fullCode := model.FullCode()
-		if !protocol.IsStock(fullCode) {
-			continue
-		}
-		if _, ok := seen[model.Code]; ok {
-			continue
-		}
-
-		codeUpper := strings.ToUpper(model.Code)
-		nameUpper := strings.ToUpper(model.Name)
-		if strings.Contains(codeUpper, keywordUpper) || strings.Contains(nameUpper, keywordUpper) {
-			results = append(results, map[string]string{
-				"code":     model.Code,
-				"name":     model.Name,
-				"exchange": strings.ToLower(model.Exchange),
-			})
-			seen[model.Code] = struct{}{}
-		}
+	codes := tdx.DefaultCodes
+	if codes == nil || codes.Len() == 0 {
+		errorResponse(w, "代码库未加载,请稍后再试")
+		return
+	}
 
-		if len(results) >= 50 {
-			break
-		}
+	results := []map[string]string{}
+	for _, model := range codes.Search(keyword, limit, types...) {
+		results = append(results, map[string]string{
+			"code":     model.Code,
+			"name":     model.Name,
+			"exchange": strings.ToLower(model.Exchange),
+			"type":     string(model.SecurityType),
+			"typeName": model.SecurityType.Name(),
+		})
 	}
 
 	successResponse(w, results)
@@ -645,6 +671,36 @@ func handleTaskOperations(w http.ResponseWriter, r *http.Request) {
 	errorResponse(w, "任务不存在")
 }
 
+// withSymbol 统一请求参数code/codes中的代码格式,例000001.SZ,1.000001,index:000001转换成sz000001,
+// 只有6位的代码保持不变,由各接口按自身的规则处理(例指数接口的000001是上证指数)
+func withSymbol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		changed := false
+		for _, key := range []string{"code", "codes"} {
+			value := query.Get(key)
+			if value == "" {
+				continue
+			}
+			ls := splitCodes(value)
+			for i, code := range ls {
+				if len(code) == 6 {
+					continue
+				}
+				if symbol, err := protocol.ParseSymbol(code); err == nil && symbol.String() != code {
+					ls[i] = symbol.String()
+					changed = true
+				}
+			}
+			query.Set(key, strings.Join(ls, ","))
+		}
+		if changed {
+			r.URL.RawQuery = query.Encode()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func splitCodes(param string) []string {
 	parts := strings.Split(param, ",")
 	result := make([]string, 0, len(parts))
@@ -713,6 +769,7 @@ func main() {
 	http.HandleFunc("/api/kline", handleGetKline)
 	http.HandleFunc("/api/minute", handleGetMinute)
 	http.HandleFunc("/api/trade", handleGetTrade)
+	http.HandleFunc("/api/auction", handleGetAuction)
 	http.HandleFunc("/api/search", handleSearchCode)
 	http.HandleFunc("/api/stock-info", handleGetStockInfo)
 	http.HandleFunc("/api/codes", handleGetCodes)
@@ -727,6 +784,8 @@ func main() {
 	http.HandleFunc("/api/server-status", handleGetServerStatus)
 	http.HandleFunc("/api/health", handleHealthCheck)
 	http.HandleFunc("/api/etf", handleGetETFList)
+	http.HandleFunc("/api/sectors", handleGetSectors)
+	http.HandleFunc("/api/sector-members", handleGetSectorMembers)
 	http.HandleFunc("/api/trade-history", handleGetTradeHistory)
 	http.HandleFunc("/api/trade-history/full", handleGetTradeHistoryFull)
 	http.HandleFunc("/api/minute-trade-all", handleGetMinuteTradeAll)
@@ -743,5 +802,5 @@ func main() {
 
 	port := ":8080"
 	log.Printf("服务启动成功，访问 http://localhost%s\n", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	log.Fatal(http.ListenAndServe(port, withSymbol(http.DefaultServeMux)))
 }
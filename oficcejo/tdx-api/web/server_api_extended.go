@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -20,6 +21,11 @@ import (
 // 获取股票代码列表
 func handleGetCodes(w http.ResponseWriter, r *http.Request) {
 	exchange := r.URL.Query().Get("exchange")
+	filter, err := parseTypeFilter(r)
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
 
 	type CodesResponse struct {
 		Total     int                 `json:"total"`
@@ -44,8 +50,7 @@ func handleGetCodes(w http.ResponseWriter, r *http.Request) {
 	targetExchange := strings.ToLower(exchange)
 
 	for _, model := range allCodes {
-		fullCode := model.FullCode()
-		if !protocol.IsStock(fullCode) {
+		if !filter(model) {
 			continue
 		}
 		exName := strings.ToLower(model.Exchange)
@@ -59,6 +64,7 @@ func handleGetCodes(w http.ResponseWriter, r *http.Request) {
 			"code":     model.Code,
 			"name":     model.Name,
 			"exchange": exName,
+			"type":     string(model.SecurityType),
 		})
 	}
 
@@ -258,7 +264,7 @@ func handleGetIndexAll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	limit := parsePositiveInt(r.URL.Query().Get("limit"))
-	list, err := fetchIndexAll(code, klineType)
+	list, err := fetchIndexAll(r.Context(), code, klineType)
 	if err != nil {
 		errorResponse(w, fmt.Sprintf("获取指数历史数据失败: %v", err))
 		return
@@ -298,6 +304,12 @@ func handleGetMarketStats(w http.ResponseWriter, r *http.Request) {
 		UpdateTime string `json:"update_time"`
 	}
 
+	filter, err := parseTypeFilter(r)
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
 	stats := &MarketStats{}
 	allCodes, err := getAllCodeModels()
 	if err != nil {
@@ -306,8 +318,7 @@ func handleGetMarketStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, model := range allCodes {
-		fullCode := model.FullCode()
-		if !protocol.IsStock(fullCode) {
+		if !filter(model) {
 			continue
 		}
 		lastPrice := model.LastPrice
@@ -432,6 +443,78 @@ func handleGetETFCodes(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// 获取板块列表，或者指定股票所属的板块
+func handleGetSectors(w http.ResponseWriter, r *http.Request) {
+	if tdx.DefaultCodes == nil {
+		errorResponse(w, "代码缓存未初始化")
+		return
+	}
+
+	code := strings.TrimSpace(r.URL.Query().Get("code"))
+	kind := strings.TrimSpace(r.URL.Query().Get("kind"))
+
+	type SectorItem struct {
+		Name  string `json:"name"`
+		Kind  string `json:"kind"`
+		Count int    `json:"count"`
+	}
+
+	var sectors []*tdx.Sector
+	if code != "" {
+		sectors = tdx.DefaultCodes.Sectors(code)
+	} else if kind != "" && kind != "all" {
+		sectors = tdx.DefaultCodes.GetSectors(kind)
+	} else {
+		sectors = tdx.DefaultCodes.GetSectors()
+	}
+
+	list := make([]SectorItem, 0, len(sectors))
+	for _, v := range sectors {
+		if code != "" && kind != "" && kind != "all" && v.Kind != kind {
+			continue
+		}
+		list = append(list, SectorItem{
+			Name:  v.Name,
+			Kind:  v.Kind,
+			Count: len(v.Codes),
+		})
+	}
+
+	successResponse(w, map[string]interface{}{
+		"count": len(list),
+		"list":  list,
+	})
+}
+
+// 获取板块成分股
+func handleGetSectorMembers(w http.ResponseWriter, r *http.Request) {
+	if tdx.DefaultCodes == nil {
+		errorResponse(w, "代码缓存未初始化")
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		errorResponse(w, "板块名称不能为空")
+		return
+	}
+
+	codes := tdx.DefaultCodes.Members(name)
+	list := make([]map[string]string, 0, len(codes))
+	for _, code := range codes {
+		list = append(list, map[string]string{
+			"code": code,
+			"name": tdx.DefaultCodes.GetName(code),
+		})
+	}
+
+	successResponse(w, map[string]interface{}{
+		"name":  name,
+		"count": len(list),
+		"list":  list,
+	})
+}
+
 // 获取ETF列表
 func handleGetETFList(w http.ResponseWriter, r *http.Request) {
 	exchangeFilter := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("exchange")))
@@ -544,9 +627,9 @@ func handleGetMinuteTradeAll(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if date != "" {
-		resp, err = client.GetHistoryMinuteTradeDay(date, code)
+		resp, err = client.GetHistoryMinuteTradeDayContext(r.Context(), date, code)
 	} else {
-		resp, err = client.GetMinuteTradeAll(code)
+		resp, err = client.GetMinuteTradeAllContext(r.Context(), code)
 	}
 	if err != nil {
 		errorResponse(w, fmt.Sprintf("获取分时成交失败: %v", err))
@@ -634,10 +717,11 @@ func handleGetTradeHistoryFull(w http.ResponseWriter, r *http.Request) {
 			time.Date(historyEnd.Year(), historyEnd.Month(), historyEnd.Day(), 15, 0, 0, 0, time.Local).Add(24*time.Hour),
 			func(t time.Time) bool {
 				dateStr := t.Format("20060102")
-				resp, err := client.GetHistoryMinuteTradeDay(dateStr, code)
+				resp, err := client.GetHistoryMinuteTradeDayContext(r.Context(), dateStr, code)
 				if err != nil {
 					lastErr = err
-					return true
+					//请求已取消,不再继续获取后续日期
+					return r.Context().Err() == nil
 				}
 				if resp == nil || len(resp.List) == 0 {
 					return true
@@ -663,7 +747,7 @@ func handleGetTradeHistoryFull(w http.ResponseWriter, r *http.Request) {
 
 	if includeToday && !truncated {
 		now := time.Now()
-		resp, err := client.GetMinuteTradeAll(code)
+		resp, err := client.GetMinuteTradeAllContext(r.Context(), code)
 		if err == nil && resp != nil && len(resp.List) > 0 {
 			dateStr := now.Format("20060102")
 			daysCovered = append(daysCovered, dateStr)
@@ -716,7 +800,7 @@ func handleGetKlineAllTDX(w http.ResponseWriter, r *http.Request) {
 	}
 	limit := parsePositiveInt(r.URL.Query().Get("limit"))
 
-	list, err := fetchStockKlineAllTDX(code, klineType)
+	list, err := fetchStockKlineAllTDX(r.Context(), code, klineType)
 	if err != nil {
 		errorResponse(w, fmt.Sprintf("获取K线失败: %v", err))
 		return
@@ -865,10 +949,11 @@ func handleGetWorkdayRange(w http.ResponseWriter, r *http.Request) {
 // 获取服务器状态
 func handleGetServerStatus(w http.ResponseWriter, r *http.Request) {
 	type ServerStatus struct {
-		Status    string `json:"status"`
-		Connected bool   `json:"connected"`
-		Version   string `json:"version"`
-		Uptime    string `json:"uptime"`
+		Status    string          `json:"status"`
+		Connected bool            `json:"connected"`
+		Version   string          `json:"version"`
+		Uptime    string          `json:"uptime"`
+		Pool      []*tdx.PoolStat `json:"pool,omitempty"` //连接池中每个客户端的耗时和错误次数
 	}
 
 	status := &ServerStatus{
@@ -877,6 +962,9 @@ func handleGetServerStatus(w http.ResponseWriter, r *http.Request) {
 		Version:   "1.0.0",
 		Uptime:    "unknown",
 	}
+	if manager != nil {
+		status.Pool = manager.Stats()
+	}
 
 	successResponse(w, status)
 }
@@ -964,6 +1052,39 @@ func handleGetIncome(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// parseTypes 解析type参数,多个用逗号分隔,例stock,etf,为空时使用def,all表示全部(返回nil)
+func parseTypes(r *http.Request, def string) ([]protocol.SecurityType, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("type"))
+	if value == "" {
+		value = def
+	}
+	types := []protocol.SecurityType(nil)
+	for _, v := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "all") {
+			return nil, nil
+		}
+		t, ok := protocol.ParseSecurityType(v)
+		if !ok {
+			return nil, fmt.Errorf("未知的证券类型: %s", v)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// parseTypeFilter 同parseTypes,为空默认只要股票,返回过滤函数
+func parseTypeFilter(r *http.Request) (func(model *tdx.CodeModel) bool, error) {
+	types, err := parseTypes(r, string(protocol.SecurityStock))
+	if err != nil {
+		return nil, err
+	}
+	m := map[protocol.SecurityType]bool{}
+	for _, v := range types {
+		m[v] = true
+	}
+	return func(model *tdx.CodeModel) bool { return len(types) == 0 || m[model.SecurityType] }, nil
+}
+
 func getAllCodeModels() ([]*tdx.CodeModel, error) {
 	if tdx.DefaultCodes != nil {
 		if list, err := tdx.DefaultCodes.GetCodes(true); err == nil && len(list) > 0 {
@@ -984,12 +1105,13 @@ func getAllCodeModels() ([]*tdx.CodeModel, error) {
 		}
 		for _, v := range resp.List {
 			aggregate = append(aggregate, &tdx.CodeModel{
-				Name:      v.Name,
-				Code:      v.Code,
-				Exchange:  ex.String(),
-				Multiple:  v.Multiple,
-				Decimal:   v.Decimal,
-				LastPrice: v.LastPrice,
+				Name:         v.Name,
+				Code:         v.Code,
+				Exchange:     ex.String(),
+				SecurityType: protocol.SecurityTypeOf(ex.String() + v.Code),
+				Multiple:     v.Multiple,
+				Decimal:      v.Decimal,
+				LastPrice:    v.LastPrice,
 			})
 		}
 	}
@@ -1111,64 +1233,64 @@ func parseBool(value string) bool {
 	}
 }
 
-func fetchStockKlineAllTDX(code, klineType string) ([]*protocol.Kline, error) {
+func fetchStockKlineAllTDX(ctx context.Context, code, klineType string) ([]*protocol.Kline, error) {
 	switch strings.ToLower(klineType) {
 	case "minute1":
-		resp, err := client.GetKlineMinuteAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineMinute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute5":
-		resp, err := client.GetKline5MinuteAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKline5Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute15":
-		resp, err := client.GetKline15MinuteAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKline15Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute30":
-		resp, err := client.GetKline30MinuteAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKline30Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "hour":
-		resp, err := client.GetKlineHourAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKline60Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "day":
-		resp, err := client.GetKlineDayAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineDay, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "week":
-		resp, err := client.GetKlineWeekAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineWeek, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "month":
-		resp, err := client.GetKlineMonthAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineMonth, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "quarter":
-		resp, err := client.GetKlineQuarterAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineQuarter, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "year":
-		resp, err := client.GetKlineYearAll(code)
+		resp, err := client.GetKlineAllContext(ctx, protocol.TypeKlineYear, code)
 		if err != nil {
 			return nil, err
 		}
@@ -1179,7 +1301,7 @@ func fetchStockKlineAllTDX(code, klineType string) ([]*protocol.Kline, error) {
 }
 
 func fetchStockKlineAllTHS(code, klineType string) ([]*protocol.Kline, error) {
-	resp, err := getQfqKlineDay(code)
+	resp, err := getThsQfqKlineDay(code)
 	if err != nil {
 		return nil, err
 	}
@@ -1227,58 +1349,58 @@ func respondKlineSuccess(w http.ResponseWriter, source, klineType string, list [
 	})
 }
 
-func fetchIndexAll(code, klineType string) ([]*protocol.Kline, error) {
+func fetchIndexAll(ctx context.Context, code, klineType string) ([]*protocol.Kline, error) {
 	switch strings.ToLower(klineType) {
 	case "minute1":
-		resp, err := client.GetIndexAll(protocol.TypeKlineMinute, code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineMinute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute5":
-		resp, err := client.GetIndexAll(protocol.TypeKline5Minute, code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKline5Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute15":
-		resp, err := client.GetIndexAll(protocol.TypeKline15Minute, code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKline15Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "minute30":
-		resp, err := client.GetIndexAll(protocol.TypeKline30Minute, code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKline30Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "hour":
-		resp, err := client.GetIndexAll(protocol.TypeKline60Minute, code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKline60Minute, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "week":
-		resp, err := client.GetIndexWeekAll(code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineWeek, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "month":
-		resp, err := client.GetIndexMonthAll(code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineMonth, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "quarter":
-		resp, err := client.GetIndexQuarterAll(code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineQuarter, code)
 		if err != nil {
 			return nil, err
 		}
 		return resp.List, nil
 	case "year":
-		resp, err := client.GetIndexYearAll(code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineYear, code)
 		if err != nil {
 			return nil, err
 		}
@@ -1286,7 +1408,7 @@ func fetchIndexAll(code, klineType string) ([]*protocol.Kline, error) {
 	case "day":
 		fallthrough
 	default:
-		resp, err := client.GetIndexDayAll(code)
+		resp, err := client.GetIndexAllContext(ctx, protocol.TypeKlineDay, code)
 		if err != nil {
 			return nil, err
 		}
@@ -82,10 +82,13 @@ function displaySearchResults(results) {
         div.innerHTML = `
             <span class="search-item-code">${item.code}</span>
             <span class="search-item-name">${item.name}</span>
+            <span class="search-item-type">${item.typeName || ''}</span>
         `;
+        // 同一个代码可能是不同交易所的股票和指数,需要带上交易所
+        const fullCode = (item.exchange || '') + item.code;
         div.onclick = () => {
-            currentStock = item.code;
-            loadStockData(item.code);
+            currentStock = fullCode;
+            loadStockData(fullCode);
             container.innerHTML = '';
         };
         container.appendChild(div);
@@ -385,6 +388,17 @@ function displayKline(data, type) {
                     borderColor0: '#14b143'
                 }
             },
+            {
+                name: '均价',
+                type: 'line',
+                data: avgPrices,
+                smooth: true,
+                symbol: 'none',
+                lineStyle: {
+                    color: '#faad14',
+                    width: 1
+                }
+            },
             {
                 name: '成交量',
                 type: 'bar',
@@ -431,11 +445,15 @@ function displayMinute(data) {
     
     const times = [];
     const prices = [];
+    const avgPrices = [];
     const volumes = [];
     
     data.List.forEach(item => {
-        times.push(item.Time);
+        const time = new Date(item.Time);
+        times.push(String(time.getHours()).padStart(2, '0') + ':' +
+                   String(time.getMinutes()).padStart(2, '0'));
         prices.push((parseFloat(item.Price) / 1000).toFixed(2));
+        avgPrices.push((parseFloat(item.AvgPrice) / 1000).toFixed(2));
         volumes.push(item.Number);
     });
     